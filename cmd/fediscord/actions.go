@@ -1,19 +1,24 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
-
-	"github.com/jimed-rand/fediscord/internal/config"
-	"github.com/jimed-rand/fediscord/internal/discord"
-	"github.com/jimed-rand/fediscord/internal/fediverse"
-	"github.com/jimed-rand/fediscord/internal/storage"
-	"github.com/jimed-rand/fediscord/internal/ui"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/jimed-rand/fediscord/pkg/config"
+	"github.com/jimed-rand/fediscord/pkg/discord"
+	"github.com/jimed-rand/fediscord/pkg/fediverse"
+	"github.com/jimed-rand/fediscord/pkg/storage"
+	"github.com/jimed-rand/fediscord/pkg/terminal"
+	"github.com/jimed-rand/fediscord/pkg/ui"
 )
 
 func askAndStoreToken(paths *config.Paths) error {
-	useEncryption, err := askEncryptionPreference(paths)
-	if err != nil {
+	if err := askEncryptionPreference(paths); err != nil {
 		return err
 	}
 
@@ -25,67 +30,119 @@ func askAndStoreToken(paths *config.Paths) error {
 		return errors.New("token cannot be empty")
 	}
 
-	return storeToken(paths, token, useEncryption)
+	return storeToken(paths, token)
 }
 
-func askEncryptionPreference(paths *config.Paths) (bool, error) {
-	if _, err := storage.IsEncryptionEnabled(paths); err == nil {
-		return storage.IsEncryptionEnabled(paths)
+func askEncryptionPreference(paths *config.Paths) error {
+	if _, err := storage.ActiveBackend(paths); err == nil {
+		return nil
 	}
 
-	if !storage.IsGPGAvailable() {
-		ui.Warn("GPG is not installed. Token will be stored in plain text (INSECURE).")
-		ui.Info("  To enable encryption, install GPG:")
-		ui.Info("    Ubuntu/Debian: sudo apt install gnupg")
-		ui.Info("    Fedora:        sudo dnf install gnupg")
-		ui.Info("    Arch:          sudo pacman -S gnupg")
-		fmt.Println()
-		if !ui.Confirm("Continue with plain text storage? (yes/no): ") {
-			return false, errors.New("setup cancelled")
-		}
-		storage.SetEncryptionPreference(paths, false)
-		return false, nil
+	backend, err := chooseBackend(paths)
+	if err != nil {
+		return err
 	}
+	if err := storage.SetBackendPreference(paths, backend.Name()); err != nil {
+		return err
+	}
+	if backend.Encrypted() {
+		ui.Success("Token storage method set: " + backend.Description())
+	} else {
+		ui.Warn("Plain text storage enabled (INSECURE)")
+	}
+	return nil
+}
+
+var backendHints = map[string]string{
+	config.EncryptionAge:           "(Recommended) - Protected by a passphrase",
+	config.EncryptionGPG:           "- Protected by a passphrase through gpg",
+	config.EncryptionSecretService: "- Kept in the desktop keyring (GNOME Keyring, KWallet)",
+	config.EncryptionPass:          "- Kept in the pass password store",
+	config.EncryptionPlain:         "- No encryption (NOT RECOMMENDED)",
+}
+
+func chooseBackend(paths *config.Paths) (storage.SecretStore, error) {
+	backends := storage.Backends(paths)
 
 	ui.Info("Choose Discord token storage method:")
-	ui.Info("1) Encrypted (Recommended) - Requires GPG passphrase")
-	ui.Info("2) Plain text - No encryption (NOT RECOMMENDED)")
-	fmt.Println()
+	for i, backend := range backends {
+		line := fmt.Sprintf("%d) %s %s", i+1, backend.Description(), backendHints[backend.Name()])
+		if backend.Available() != nil {
+			line += " [unavailable]"
+		}
+		ui.Info(line)
+	}
+	ui.Info("")
 
 	for {
-		choice := ui.Prompt("Select option (1 or 2): ")
-		switch choice {
-		case "1":
-			storage.SetEncryptionPreference(paths, true)
-			ui.Success("Encryption enabled")
-			return true, nil
-		case "2":
+		choice, err := strconv.Atoi(ui.Prompt(fmt.Sprintf("Select option (1-%d): ", len(backends))))
+		if err != nil || choice < 1 || choice > len(backends) {
+			ui.Error(fmt.Sprintf("Invalid option. Please choose 1-%d.", len(backends)))
+			continue
+		}
+		backend := backends[choice-1]
+		if err := backend.Available(); err != nil {
+			ui.Error(err.Error())
+			continue
+		}
+		if !backend.Encrypted() {
 			ui.Warn("WARNING: Discord token will be stored in PLAIN TEXT!")
 			ui.Warn("Anyone with access to your home directory can read it!")
-			fmt.Println()
-			if ui.Confirm("Are you absolutely sure? (yes/no): ") {
-				storage.SetEncryptionPreference(paths, false)
-				ui.Warn("Plain text storage enabled (INSECURE)")
-				return false, nil
+			ui.Info("")
+			if !ui.Confirm("Are you absolutely sure? (yes/no): ") {
+				continue
 			}
-		default:
-			ui.Error("Invalid option. Please choose 1 or 2.")
 		}
+		return backend, nil
 	}
 }
 
-func storeToken(paths *config.Paths, token string, useEncryption bool) error {
-	if useEncryption {
-		ui.Info("Encrypting and storing Discord token...")
-		if err := storage.StoreTokenEncrypted(paths, token); err != nil {
-			return fmt.Errorf("failed to encrypt token: %w", err)
-		}
-		ui.Success("Discord token securely encrypted and stored")
+func promptPassphrase(confirm bool) (string, error) {
+	return readPassphrase(passphraseEnv, "token passphrase", confirm)
+}
+
+func readPassphrase(env, label string, confirm bool) (string, error) {
+	if passphrase := os.Getenv(env); passphrase != "" {
+		return passphrase, nil
+	}
+	if !terminal.IsInputTerminal() {
+		return "", fmt.Errorf("%w; standard input is not a terminal, so set %s", storage.ErrNoPassphrase, env)
+	}
+
+	passphrase, err := ui.PromptSecret("Enter the " + label + " (input hidden): ")
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	if !confirm || passphrase == "" {
+		return passphrase, nil
+	}
+
+	repeated, err := ui.PromptSecret("Repeat the passphrase: ")
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	if repeated != passphrase {
+		return "", errors.New("the passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+func storeToken(paths *config.Paths, token string) error {
+	backend, err := storage.ActiveBackend(paths)
+	if err != nil {
+		return err
+	}
+
+	ui.Info("Storing Discord token: " + backend.Description() + "...")
+	if backend.Name() == config.EncryptionAge {
+		ui.Info("  The passphrase will be required whenever the token is used.")
+	}
+	if err := storage.StoreToken(paths, token); err != nil {
+		return fmt.Errorf("failed to store token: %w", err)
+	}
+	if backend.Encrypted() {
+		ui.Success("Discord token securely stored: " + backend.Description())
 	} else {
-		ui.Info("Storing Discord token in plain text...")
-		if err := storage.StoreTokenPlain(paths, token); err != nil {
-			return fmt.Errorf("failed to store token: %w", err)
-		}
 		ui.Warn("Discord token stored (UNENCRYPTED - INSECURE)")
 	}
 	return nil
@@ -110,8 +167,7 @@ func setupConfiguration(paths *config.Paths) {
 	ui.Warn("  It gives FULL access to your Discord account!")
 	fmt.Println()
 
-	useEncryption, err := askEncryptionPreference(paths)
-	if err != nil {
+	if err := askEncryptionPreference(paths); err != nil {
 		ui.Error(err.Error())
 		ui.PressEnter()
 		return
@@ -124,7 +180,13 @@ func setupConfiguration(paths *config.Paths) {
 		return
 	}
 
-	if err := storeToken(paths, token, useEncryption); err != nil {
+	if err := verifyNewToken(token); err != nil {
+		ui.Error(err.Error())
+		ui.PressEnter()
+		return
+	}
+
+	if err := storeToken(paths, token); err != nil {
 		ui.Error(err.Error())
 		ui.PressEnter()
 		return
@@ -138,34 +200,20 @@ func setupConfiguration(paths *config.Paths) {
 	ui.Info("  @jimedrand@fe.disroot.org (Mastodon)")
 	ui.Info("  @user@social.example.com  (Akkoma)")
 	ui.Info("  @alice@pleroma.site       (Pleroma)")
+	ui.Info("A profile URL may be entered instead:")
+	ui.Info("  https://fe.disroot.org/@jimedrand")
+	ui.Info("  https://pleroma.site/users/alice")
 	fmt.Println()
 
-	handle := ui.Prompt("Enter your Fediverse handle: ")
-	validated, err := fediverse.ValidateHandle(handle)
-	if err != nil {
-		ui.Error(err.Error())
+	handle := ui.Prompt("Enter your Fediverse handle or profile URL: ")
+	checked, err := applyHandle(paths, handle, confirmProceed, confirmSwitch)
+	if errors.Is(err, ui.ErrCancelled) {
+		ui.Info("Setup cancelled")
 		ui.PressEnter()
 		return
 	}
-
-	instance := fediverse.ExtractInstance(validated)
-	ui.Success("Instance: " + instance)
-
-	version, err := fediverse.CheckMastodonAPISupport(instance)
 	if err != nil {
-		ui.Warn(err.Error())
-		if !ui.Confirm("Do you want to continue anyway? (yes/no): ") {
-			ui.Info("Setup cancelled")
-			ui.PressEnter()
-			return
-		}
-	} else {
-		ui.Success("Instance is running: " + version)
-		ui.Success("Instance appears to support Mastodon API")
-	}
-
-	if err := storage.StoreHandle(paths, validated); err != nil {
-		ui.Error("Failed to save handle: " + err.Error())
+		ui.Error(err.Error())
 		ui.PressEnter()
 		return
 	}
@@ -174,7 +222,7 @@ func setupConfiguration(paths *config.Paths) {
 	ui.Separator()
 	ui.Success("Configuration completed successfully!")
 	ui.Success("  Discord token: Stored")
-	ui.Success("  Fediverse handle: @" + validated)
+	ui.Success("  Fediverse handle: @" + checked.Handle)
 	ui.Separator()
 	fmt.Println()
 	ui.Info("Next step: Use option 2 to generate connection URL")
@@ -184,16 +232,9 @@ func setupConfiguration(paths *config.Paths) {
 func generateConnectionURL(paths *config.Paths) {
 	ui.PrintHeader("Generate Connection URL")
 
-	token, err := storage.RetrieveToken(paths)
+	token, handle, err := loadCredentials(paths)
 	if err != nil {
-		ui.Error("No Discord token found. Please setup configuration first (Option 1)")
-		ui.PressEnter()
-		return
-	}
-
-	handle, err := storage.RetrieveHandle(paths)
-	if err != nil {
-		ui.Error("No Fediverse handle found. Please setup configuration first (Option 1)")
+		ui.Error(err.Error() + "; please set up the configuration first (Option 1)")
 		ui.PressEnter()
 		return
 	}
@@ -202,14 +243,14 @@ func generateConnectionURL(paths *config.Paths) {
 	ui.Info("  Fediverse handle: @" + handle)
 	fmt.Println()
 
-	authURL, err := discord.GenerateConnectionURL(handle, token)
+	ctx, stop := interruptContext()
+	defer stop()
+
+	authURL, err := newDiscordClient(token).GenerateConnectionURL(ctx, handle)
 	if err != nil {
 		ui.Error(err.Error())
 		ui.Info("")
-		ui.Info("Possible reasons:")
-		ui.Info("  1. Invalid Discord token")
-		ui.Info("  2. Discord API endpoint changed")
-		ui.Info("  3. Network connectivity issues")
+		printDiscordFailureReasons(err)
 		ui.PressEnter()
 		return
 	}
@@ -237,23 +278,54 @@ func generateConnectionURL(paths *config.Paths) {
 
 func viewConfiguration(paths *config.Paths) {
 	ui.PrintHeader("Stored Configuration")
+	printConfiguration(paths)
+	if _, err := storage.LocateToken(paths); err == nil && ui.Confirm("Show a preview of the Discord token? (yes/no): ") {
+		printTokenPreview(paths)
+	}
+	fmt.Println()
+	ui.PressEnter()
+}
+
+func tokenPreview(paths *config.Paths) (string, error) {
+	backend, err := storage.LocateToken(paths)
+	if err != nil {
+		return "", err
+	}
+	token, err := backend.Retrieve()
+	if err != nil {
+		return "", fmt.Errorf("the token could not be read from the %s: %w", backend.Description(), err)
+	}
+	if len(token) > 10 {
+		token = token[:10]
+	}
+	return token + "...", nil
+}
 
+func printTokenPreview(paths *config.Paths) error {
+	preview, err := tokenPreview(paths)
+	if err != nil {
+		ui.Error("Discord Token Preview: " + err.Error())
+		return err
+	}
+	ui.Info("Discord Token Preview: " + preview)
+	return nil
+}
+
+func printConfiguration(paths *config.Paths) {
 	hasConfig := false
 
-	token, err := storage.RetrieveToken(paths)
+	ui.Info("Profile: " + paths.Profile)
+	fmt.Println()
+
+	backend, err := storage.LocateToken(paths)
 	if err == nil {
 		hasConfig = true
 		ui.Success("Discord Token: [STORED]")
-		if storage.IsEncryptedTokenPresent(paths) {
-			ui.Info("  Storage: Encrypted (GPG) - SECURE")
-		} else if storage.IsPlainTokenPresent(paths) {
+		if backend.Encrypted() {
+			ui.Info("  Storage: " + backend.Description() + " - SECURE")
+		} else {
 			ui.Warn("  Storage: Plain text - INSECURE")
 		}
-		preview := token
-		if len(preview) > 10 {
-			preview = preview[:10]
-		}
-		ui.Info("  Preview: " + preview + "...")
 	} else {
 		ui.Error("Discord Token: [NOT SET]")
 	}
@@ -271,14 +343,31 @@ func viewConfiguration(paths *config.Paths) {
 
 	fmt.Println()
 
+	printProfiles(paths)
+
+	fmt.Println()
+
+	ui.Info("Configuration file: " + paths.ConfigFile)
+	if settings.API.Discord != "" {
+		ui.Info("  Discord API: " + settings.API.Discord)
+	}
+	if settings.Preferences.SkipTokenVerification {
+		ui.Info("  Token verification: disabled")
+	}
+	if settings.Preferences.FollowMovedAccounts {
+		ui.Info("  Moved accounts: followed automatically")
+	}
+	if settings.Preferences.RecordHistory {
+		ui.Info("  Change history: " + paths.HistoryFile)
+	}
+
+	fmt.Println()
+
 	if !hasConfig {
 		ui.Separator()
 		ui.Warn("No configuration found. Please use Option 1 to setup.")
 		ui.Separator()
 	}
-
-	fmt.Println()
-	ui.PressEnter()
 }
 
 func updateDiscordToken(paths *config.Paths) {
@@ -287,8 +376,6 @@ func updateDiscordToken(paths *config.Paths) {
 	ui.Info("This will replace your current Discord token.")
 	fmt.Println()
 
-	useEncryption, _ := storage.IsEncryptionEnabled(paths)
-
 	token, err := ui.PromptSecret("Enter new Discord token (input hidden): ")
 	if err != nil || token == "" {
 		ui.Error("Token cannot be empty")
@@ -296,7 +383,13 @@ func updateDiscordToken(paths *config.Paths) {
 		return
 	}
 
-	if err := storeToken(paths, token, useEncryption); err != nil {
+	if err := verifyNewToken(token); err != nil {
+		ui.Error(err.Error())
+		ui.PressEnter()
+		return
+	}
+
+	if err := storeToken(paths, token); err != nil {
 		ui.Error(err.Error())
 		ui.PressEnter()
 		return
@@ -308,83 +401,197 @@ func updateDiscordToken(paths *config.Paths) {
 	ui.PressEnter()
 }
 
-func updateFediverseHandle(paths *config.Paths) {
-	ui.PrintHeader("Update Fediverse Handle")
+func verifyDiscordToken(paths *config.Paths) {
+	ui.PrintHeader("Verify Discord Token")
 
-	ui.Info("This will replace your current Fediverse handle.")
+	token, err := storage.RetrieveToken(paths)
+	if err != nil {
+		ui.Error(err.Error() + "; please set up the configuration first (Option 1)")
+		ui.PressEnter()
+		return
+	}
+
+	ui.Info("Checking the stored token with Discord...")
 	fmt.Println()
 
-	handle := ui.Prompt("Enter new Fediverse handle: ")
-	validated, err := fediverse.ValidateHandle(handle)
-	if err != nil {
+	if _, err := checkToken(token, func(err error) error { return err }); err != nil {
 		ui.Error(err.Error())
+		if errors.Is(err, discord.ErrUnauthorized) {
+			ui.Info("  The token has been invalidated, for example by a password change.")
+			ui.Info("  Retrieve a new token and store it with Option 4.")
+		}
 		ui.PressEnter()
 		return
 	}
 
-	instance := fediverse.ExtractInstance(validated)
-	ui.Success("Instance: " + instance)
+	fmt.Println()
+	ui.Success("The stored Discord token is valid")
+	fmt.Println()
+	ui.PressEnter()
+}
+
+func listDiscordConnections(paths *config.Paths) {
+	ui.PrintHeader("Discord Connections")
 
-	version, err := fediverse.CheckMastodonAPISupport(instance)
+	token, err := storage.RetrieveToken(paths)
 	if err != nil {
-		ui.Warn(err.Error())
-	} else {
-		ui.Success("Instance is running: " + version)
+		ui.Error(err.Error() + "; please set up the configuration first (Option 1)")
+		ui.PressEnter()
+		return
 	}
+	handle, _ := storage.RetrieveHandle(paths)
 
-	if err := storage.StoreHandle(paths, validated); err != nil {
-		ui.Error("Failed to save handle: " + err.Error())
+	connections, err := fetchConnections(token)
+	if err != nil {
+		ui.Error(err.Error())
+		ui.Info("")
+		printDiscordFailureReasons(err)
 		ui.PressEnter()
 		return
 	}
 
-	fmt.Println()
-	ui.Success("Fediverse handle updated to: @" + validated)
+	printConnections(connections, handle)
 	fmt.Println()
 	ui.PressEnter()
 }
 
-func changeEncryption(paths *config.Paths) {
-	ui.PrintHeader("Change Encryption Settings")
+func manageMastodonConnection(paths *config.Paths) {
+	ui.PrintHeader("Manage Mastodon Connection")
 
-	_ = storage.SetEncryptionPreference(paths, false)
+	token, err := storage.RetrieveToken(paths)
+	if err != nil {
+		ui.Error(err.Error() + "; please set up the configuration first (Option 1)")
+		ui.PressEnter()
+		return
+	}
+	handle, _ := storage.RetrieveHandle(paths)
 
-	if storage.IsEncryptedTokenPresent(paths) || storage.IsPlainTokenPresent(paths) {
-		ui.Warn("Existing Discord token found.")
-		ui.Warn("  It will be re-encrypted or decrypted based on your new choice.")
-		fmt.Println()
+	connections, err := fetchConnections(token)
+	if err != nil {
+		ui.Error(err.Error())
+		ui.Info("")
+		printDiscordFailureReasons(err)
+		ui.PressEnter()
+		return
+	}
 
-		token, err := storage.RetrieveToken(paths)
-		if err != nil {
-			ui.Error("Failed to read existing token: " + err.Error())
-			ui.PressEnter()
-			return
-		}
+	conn, err := chooseMastodonConnection(connections, handle)
+	if err != nil {
+		ui.Error(err.Error())
+		ui.PressEnter()
+		return
+	}
 
-		useEncryption, err := askEncryptionPreference(paths)
-		if err != nil {
-			ui.Error(err.Error())
-			ui.PressEnter()
-			return
+	fmt.Println()
+	ui.Success("Selected connection: " + conn.Name)
+	ui.Info("1) Toggle profile visibility (currently: " + visibilityLabel(conn.Visibility) + ")")
+	ui.Info("2) Toggle activity display (currently: " + yesNo(conn.ShowActivity) + ")")
+	ui.Info("3) Remove connection")
+	ui.Info("4) Back to main menu")
+	fmt.Println()
+
+	switch ui.Prompt("Select an option (1-4): ") {
+	case "1":
+		visibility := discord.VisibilityEveryone
+		if conn.Visibility == discord.VisibilityEveryone {
+			visibility = discord.VisibilityOnlyMe
+		}
+		err = updateConnection(token, conn, discord.ConnectionUpdate{Visibility: &visibility})
+	case "2":
+		showActivity := !conn.ShowActivity
+		err = updateConnection(token, conn, discord.ConnectionUpdate{ShowActivity: &showActivity})
+	case "3":
+		ui.Warn("WARNING: This will remove the connection " + conn.Name + " from your Discord profile.")
+		ui.Warn("It must be authorised again (Option 2) to be restored.")
+		fmt.Println()
+		if !confirmDeletion() {
+			ui.Info("Removal cancelled")
+			break
 		}
+		err = removeConnection(token, conn)
+	default:
+		return
+	}
+	if err != nil {
+		ui.Error(err.Error())
+	}
 
-		if err := storeToken(paths, token, useEncryption); err != nil {
-			ui.Error(err.Error())
-			ui.PressEnter()
-			return
+	fmt.Println()
+	ui.PressEnter()
+}
+
+func showInstanceReport(paths *config.Paths) {
+	ui.PrintHeader("Instance Report")
+
+	stored, _ := storage.RetrieveHandle(paths)
+	handle := stored
+	if stored != "" {
+		ui.Info("Stored Fediverse handle: @" + stored)
+		handle = ui.Prompt("Enter a Fediverse handle (leave blank for the stored handle): ")
+		if handle == "" {
+			handle = stored
 		}
+	} else {
+		handle = ui.Prompt("Enter a Fediverse handle: ")
+	}
+	fmt.Println()
+
+	checked, info, err := fetchInstanceReport(paths, handle)
+	if err != nil {
+		ui.Error(err.Error())
+		ui.PressEnter()
+		return
+	}
+
+	printInstanceReport(checked, info)
+	fmt.Println()
+	ui.PressEnter()
+}
+
+func updateFediverseHandle(paths *config.Paths) {
+	ui.PrintHeader("Update Fediverse Handle")
+
+	ui.Info("This will replace your current Fediverse handle.")
+	fmt.Println()
+
+	handle := ui.Prompt("Enter new Fediverse handle or profile URL: ")
+	checked, err := applyHandle(paths, handle, confirmProceed, confirmSwitch)
+	if errors.Is(err, ui.ErrCancelled) {
+		ui.Info("Fediverse handle not changed")
+		ui.PressEnter()
+		return
+	}
+	if err != nil {
+		ui.Error(err.Error())
+		ui.PressEnter()
+		return
+	}
 
-		ui.Success("Encryption settings updated successfully!")
+	fmt.Println()
+	ui.Success("Fediverse handle updated to: @" + checked.Handle)
+	fmt.Println()
+	ui.PressEnter()
+}
+
+func changeEncryption(paths *config.Paths) {
+	ui.PrintHeader("Change Encryption Settings")
+
+	if current, err := storage.LocateToken(paths); err == nil {
+		ui.Warn("Existing Discord token found: " + current.Description())
+		ui.Warn("  It will be moved to the storage method you choose.")
 	} else {
 		ui.Info("No existing Discord token found.")
-		fmt.Println()
-		_, err := askEncryptionPreference(paths)
-		if err != nil {
-			ui.Error(err.Error())
-			ui.PressEnter()
-			return
-		}
-		ui.Success("Encryption preference saved for future tokens")
+	}
+	fmt.Println()
+
+	backend, err := chooseBackend(paths)
+	if err == nil {
+		err = migrateToken(paths, backend.Name())
+	}
+	if err != nil {
+		ui.Error(err.Error())
+		ui.PressEnter()
+		return
 	}
 
 	fmt.Println()
@@ -394,7 +601,7 @@ func changeEncryption(paths *config.Paths) {
 func deleteAllData(paths *config.Paths) {
 	ui.PrintHeader("Delete All Data")
 
-	ui.Warn("WARNING: This will PERMANENTLY delete:")
+	ui.Warn("WARNING: This will PERMANENTLY delete, for every profile:")
 	ui.Info("  Your Discord token")
 	ui.Info("  Your Fediverse handle")
 	ui.Info("  All encryption settings")
@@ -403,13 +610,9 @@ func deleteAllData(paths *config.Paths) {
 	ui.Warn("This action CANNOT be undone!")
 	fmt.Println()
 
-	confirm := ui.Prompt("Type 'DELETE' to confirm: ")
-	if confirm == "DELETE" {
-		if err := storage.DeleteAll(paths); err != nil {
+	if confirmDeletion() {
+		if _, err := purgeData(paths); err != nil {
 			ui.Error("Failed to delete data: " + err.Error())
-		} else {
-			ui.Success("All data deleted successfully")
-			ui.Info("  Configuration directory removed: " + paths.Dir)
 		}
 	} else {
 		ui.Info("Deletion cancelled")
@@ -418,3 +621,319 @@ func deleteAllData(paths *config.Paths) {
 	fmt.Println()
 	ui.PressEnter()
 }
+
+var errNoMastodonConnection = errors.New("no Mastodon connection is linked to this Discord account")
+
+func interruptContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
+func newDiscordClient(token string) *discord.Client {
+	client := discord.NewClient(token, discord.OnRetry(reportRetry))
+	if settings.API.Discord != "" {
+		client.BaseURL = settings.API.Discord
+	}
+	client.UserAgent = discord.DefaultUserAgent + "/" + version
+	return client
+}
+
+func reportRetry(event discord.RetryEvent) {
+	ui.Warn(fmt.Sprintf("Discord request failed: %s; retrying in %s (attempt %d of %d)",
+		event.Reason, event.Wait.Round(100*time.Millisecond), event.Attempt+1, event.MaxAttempts))
+}
+
+func checkToken(token string, proceed func(error) error) (*discord.User, error) {
+	ctx, stop := interruptContext()
+	defer stop()
+
+	user, err := newDiscordClient(token).CurrentUser(ctx)
+	if err == nil {
+		ui.Success("Token belongs to Discord user: " + user.Tag() + " (ID " + user.ID + ")")
+		return user, nil
+	}
+	if errors.Is(err, discord.ErrUnauthorized) || errors.Is(err, context.Canceled) {
+		return nil, err
+	}
+
+	ui.Warn("The token could not be verified: " + err.Error())
+	return nil, proceed(err)
+}
+
+func verifyNewToken(token string) error {
+	if settings.Preferences.SkipTokenVerification {
+		ui.Warn("Token verification is disabled in the configuration file")
+		return nil
+	}
+	_, err := checkToken(token, confirmUnverifiedToken)
+	return err
+}
+
+func confirmUnverifiedToken(error) error {
+	if ui.Confirm("Store the token without verification? (yes/no): ") {
+		return nil
+	}
+	return ui.ErrCancelled
+}
+
+func fetchConnections(token string) ([]discord.Connection, error) {
+	ctx, stop := interruptContext()
+	defer stop()
+
+	return newDiscordClient(token).ListConnections(ctx)
+}
+
+func printConnections(connections []discord.Connection, handle string) {
+	if len(connections) == 0 {
+		ui.Info("No connections are linked to this Discord account.")
+	}
+
+	for _, conn := range connections {
+		if conn.Type != discord.ConnectionTypeMastodon {
+			ui.Info(fmt.Sprintf("  %-10s %s", conn.Type, conn.Name))
+			continue
+		}
+
+		ui.Success(fmt.Sprintf("%-10s %s", conn.Type, conn.Name))
+		ui.Info("  ID:            " + conn.ID)
+		ui.Info("  Verified:      " + yesNo(conn.Verified))
+		ui.Info("  Visibility:    " + visibilityLabel(conn.Visibility))
+		ui.Info("  Show activity: " + yesNo(conn.ShowActivity))
+		if conn.Revoked {
+			ui.Warn("  This connection has been revoked")
+		}
+	}
+
+	if handle == "" {
+		return
+	}
+
+	fmt.Println()
+	if isLinked(connections, handle) {
+		ui.Success("Stored Fediverse handle @" + handle + " is linked to this Discord account")
+	} else {
+		ui.Warn("Stored Fediverse handle @" + handle + " is not linked to this Discord account")
+	}
+}
+
+func isLinked(connections []discord.Connection, handle string) bool {
+	for _, conn := range connections {
+		if conn.Matches(handle) {
+			return true
+		}
+	}
+	return false
+}
+
+func chooseMastodonConnection(connections []discord.Connection, handle string) (*discord.Connection, error) {
+	var candidates []discord.Connection
+	for _, conn := range connections {
+		if conn.Type == discord.ConnectionTypeMastodon {
+			candidates = append(candidates, conn)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, errNoMastodonConnection
+	case 1:
+		return &candidates[0], nil
+	}
+
+	ui.Info("Mastodon connections on this Discord account:")
+	for i, conn := range candidates {
+		label := conn.Name
+		if handle != "" && conn.Matches(handle) {
+			label += " (stored handle)"
+		}
+		ui.Info(fmt.Sprintf("  %d) %s", i+1, label))
+	}
+	fmt.Println()
+
+	choice := ui.Prompt(fmt.Sprintf("Select a connection (1-%d): ", len(candidates)))
+	for i := range candidates {
+		if choice == fmt.Sprint(i+1) {
+			return &candidates[i], nil
+		}
+	}
+	return nil, ui.ErrCancelled
+}
+
+func findMastodonConnection(connections []discord.Connection, id, handle string) (*discord.Connection, error) {
+	var candidates []discord.Connection
+	for _, conn := range connections {
+		if conn.Type != discord.ConnectionTypeMastodon {
+			continue
+		}
+		if id != "" && conn.ID == id {
+			return &conn, nil
+		}
+		if id == "" && handle != "" && conn.Matches(handle) {
+			return &conn, nil
+		}
+		candidates = append(candidates, conn)
+	}
+
+	switch {
+	case id != "":
+		return nil, fmt.Errorf("%w with ID %q", errNoMastodonConnection, id)
+	case len(candidates) == 1:
+		return &candidates[0], nil
+	case len(candidates) == 0:
+		return nil, errNoMastodonConnection
+	default:
+		return nil, errors.New("several Mastodon connections are linked and none matches the stored handle; supply -id")
+	}
+}
+
+func updateConnection(token string, conn *discord.Connection, update discord.ConnectionUpdate) error {
+	ctx, stop := interruptContext()
+	defer stop()
+
+	updated, err := newDiscordClient(token).UpdateConnection(ctx, conn.Type, conn.ID, update)
+	if err != nil {
+		return err
+	}
+	*conn = *updated
+
+	ui.Success("Connection " + conn.Name + " updated")
+	ui.Info("  Visibility:    " + visibilityLabel(conn.Visibility))
+	ui.Info("  Show activity: " + yesNo(conn.ShowActivity))
+	return nil
+}
+
+func removeConnection(token string, conn *discord.Connection) error {
+	ctx, stop := interruptContext()
+	defer stop()
+
+	if err := newDiscordClient(token).DeleteConnection(ctx, conn.Type, conn.ID); err != nil {
+		return err
+	}
+	ui.Success("Connection " + conn.Name + " removed from the Discord account")
+	return nil
+}
+
+func confirmDeletion() bool {
+	return ui.Prompt("Type 'DELETE' to confirm: ") == "DELETE"
+}
+
+func visibilityLabel(visibility int) string {
+	if visibility == discord.VisibilityEveryone {
+		return "Everyone"
+	}
+	return "Only you"
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
+
+func printDiscordFailureReasons(err error) {
+	var apiErr *discord.APIError
+	errors.As(err, &apiErr)
+
+	switch {
+	case errors.Is(err, discord.ErrUnauthorized):
+		ui.Info("Reason: the Discord token is invalid or has been revoked.")
+		ui.Info("  Retrieve a new token and store it with Option 4.")
+	case errors.Is(err, discord.ErrForbidden):
+		ui.Info("Reason: the Discord account is not permitted to add connections.")
+		ui.Info("  Verify the account e-mail address or phone number in the Discord client.")
+	case errors.Is(err, discord.ErrBadRequest):
+		ui.Info("Reason: Discord rejected the Fediverse handle.")
+		ui.Info("  Verify the handle and update it with Option 5.")
+	case errors.Is(err, discord.ErrRateLimited):
+		ui.Info("Reason: Discord is rate limiting requests from this account.")
+		if apiErr != nil && apiErr.RetryAfter > 0 {
+			ui.Info("  Retry after " + apiErr.RetryAfterDuration().Round(time.Second).String() + ".")
+		} else {
+			ui.Info("  Wait a few minutes before retrying.")
+		}
+	case errors.Is(err, discord.ErrUnavailable):
+		ui.Info("Reason: the Discord API is experiencing an outage.")
+		ui.Info("  Check https://discordstatus.com and retry later.")
+	default:
+		ui.Info("Possible reasons:")
+		ui.Info("  1. Invalid Discord token")
+		ui.Info("  2. Discord API endpoint changed")
+		ui.Info("  3. Network connectivity issues")
+	}
+}
+
+func loadCredentials(paths *config.Paths) (string, string, error) {
+	token, err := storage.RetrieveToken(paths)
+	if err != nil {
+		return "", "", err
+	}
+
+	handle, err := storage.RetrieveHandle(paths)
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, handle, nil
+}
+
+func confirmProceed(error) error {
+	if ui.Confirm("Do you want to continue anyway? (yes/no): ") {
+		return nil
+	}
+	return ui.ErrCancelled
+}
+
+func confirmSwitch(moved string) bool {
+	if settings.Preferences.FollowMovedAccounts {
+		return true
+	}
+	return ui.Confirm("Use @" + moved + " instead? (yes/no): ")
+}
+
+func migrateToken(paths *config.Paths, name string) error {
+	_, err := storage.LocateToken(paths)
+	present := err == nil
+
+	if err := storage.MigrateToken(paths, name); err != nil {
+		return err
+	}
+	if !present {
+		ui.Success("Encryption preference saved for future tokens")
+		return nil
+	}
+	ui.Success("Encryption settings updated successfully!")
+	return nil
+}
+
+func purgeData(paths *config.Paths) (*storage.PurgeReport, error) {
+	report, err := storage.DeleteAll(paths)
+	printPurgeReport(report)
+	if err != nil {
+		return report, err
+	}
+	ui.Success("All data deleted successfully; no stored token, handle, or configuration remains")
+	ui.Warn("Backup bundles that were exported elsewhere are not removed and must be deleted separately.")
+	return report, nil
+}
+
+func printPurgeReport(report *storage.PurgeReport) {
+	if report == nil {
+		return
+	}
+	if len(report.Removed) > 0 {
+		ui.Info("Removed:")
+	}
+	for _, item := range report.Removed {
+		line := "  " + item.Item + ": " + item.Location
+		if item.Overwritten {
+			line += " (overwritten)"
+		}
+		ui.Info(line)
+	}
+	for _, kept := range report.Kept {
+		ui.Warn("Kept because it contains other files: " + kept)
+	}
+	for _, remaining := range report.Remaining {
+		ui.Error("Still present: " + remaining)
+	}
+}
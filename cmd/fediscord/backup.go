@@ -0,0 +1,262 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jimed-rand/fediscord/pkg/config"
+	"github.com/jimed-rand/fediscord/pkg/fediverse"
+	"github.com/jimed-rand/fediscord/pkg/storage"
+	"github.com/jimed-rand/fediscord/pkg/terminal"
+	"github.com/jimed-rand/fediscord/pkg/ui"
+)
+
+const bundlePassphraseEnv = "FEDISCORD_BUNDLE_PASSPHRASE"
+
+type bundleSummary struct {
+	File       string    `json:"file"`
+	Created    time.Time `json:"created"`
+	Profile    string    `json:"profile"`
+	Handle     string    `json:"handle,omitempty"`
+	Encryption string    `json:"encryption,omitempty"`
+	Token      bool      `json:"token"`
+}
+
+func summariseBundle(file string, bundle *storage.Bundle) *bundleSummary {
+	return &bundleSummary{
+		File:       file,
+		Created:    bundle.Created,
+		Profile:    bundle.Profile,
+		Handle:     bundle.Handle,
+		Encryption: bundle.Encryption,
+		Token:      bundle.Token != "",
+	}
+}
+
+func printBundle(summary *bundleSummary) {
+	ui.Info("  Created: " + summary.Created.Local().Format(time.RFC1123))
+	ui.Info("  Exported from profile: " + summary.Profile)
+	if summary.Handle != "" {
+		ui.Info("  Fediverse handle: @" + summary.Handle)
+	}
+	if summary.Encryption != "" {
+		ui.Info("  Token storage method: " + summary.Encryption)
+	}
+	ui.Info("  Discord token: " + yesNo(summary.Token))
+}
+
+func manageBackup(paths *config.Paths) {
+	for {
+		ui.PrintHeader("Export or Import Backup")
+		ui.Info("A backup bundle holds the handle, token storage method, and Discord token")
+		ui.Info("of profile " + paths.Profile + ", encrypted with a passphrase of your choice.")
+		fmt.Println()
+		ui.Info("1) Export a backup bundle")
+		ui.Info("2) Import a backup bundle")
+		ui.Info("3) Back")
+		fmt.Println()
+
+		var err error
+		switch ui.Prompt("Select an option (1-3): ") {
+		case "1":
+			err = exportBackupInteractive(paths)
+		case "2":
+			err = importBackupInteractive(paths)
+		case "3":
+			return
+		default:
+			err = errors.New("invalid option; please choose 1-3")
+		}
+
+		switch {
+		case errors.Is(err, ui.ErrCancelled):
+			ui.Info("Cancelled")
+		case err != nil:
+			ui.Error(err.Error())
+		}
+		fmt.Println()
+		ui.PressEnter()
+	}
+}
+
+func exportBackupInteractive(paths *config.Paths) error {
+	file := ui.Prompt("File to write the bundle to: ")
+	if file == "" {
+		return ui.ErrCancelled
+	}
+	if _, err := os.Stat(file); err == nil {
+		if !ui.Confirm(file + " already exists. Replace it? (yes/no): ") {
+			return ui.ErrCancelled
+		}
+	}
+	_, err := exportBackup(paths, file)
+	return err
+}
+
+func importBackupInteractive(paths *config.Paths) error {
+	file := ui.Prompt("Bundle to import: ")
+	if file == "" {
+		return ui.ErrCancelled
+	}
+	bundle, err := openBackup(file)
+	if err != nil {
+		return err
+	}
+
+	ui.Success("Bundle verified")
+	printBundle(summariseBundle(file, bundle))
+	fmt.Println()
+
+	if storage.HasData(paths) {
+		ui.Warn("Profile " + paths.Profile + " already holds a Fediverse handle or a Discord token.")
+		if !ui.Confirm("Replace them with the contents of the bundle? (yes/no): ") {
+			return ui.ErrCancelled
+		}
+	}
+
+	if bundle.Token != "" {
+		if bundle.Encryption != "" {
+			backend, _ := storage.Backend(paths, bundle.Encryption)
+			if err := backend.Available(); err != nil {
+				ui.Warn("The token storage method of the bundle cannot be used: " + err.Error())
+				chosen, err := chooseBackend(paths)
+				if err != nil {
+					return err
+				}
+				bundle.Encryption = chosen.Name()
+			}
+		} else if err := askEncryptionPreference(paths); err != nil {
+			return err
+		}
+	}
+
+	return importBackup(paths, bundle)
+}
+
+func exportBackup(paths *config.Paths, file string) (*storage.Bundle, error) {
+	passphrase, err := readPassphrase(bundlePassphraseEnv, "bundle passphrase", true)
+	if err != nil {
+		return nil, err
+	}
+	data, bundle, err := storage.ExportBundle(paths, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.WriteFileAtomic(file, data, 0600); err != nil {
+		return nil, err
+	}
+
+	ui.Success("Backup bundle written to " + file)
+	printBundle(summariseBundle(file, bundle))
+	ui.Warn("The bundle contains the Discord token; keep it and its passphrase safe.")
+	return bundle, nil
+}
+
+func openBackup(file string) (*storage.Bundle, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	passphrase, err := readPassphrase(bundlePassphraseEnv, "bundle passphrase", false)
+	if err != nil {
+		return nil, err
+	}
+	bundle, err := storage.OpenBundle(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if bundle.Handle != "" {
+		if _, err := fediverse.ValidateHandle(bundle.Handle); err != nil {
+			return nil, fmt.Errorf("%w: %v", storage.ErrBundle, err)
+		}
+	}
+	return bundle, nil
+}
+
+func importBackup(paths *config.Paths, bundle *storage.Bundle) error {
+	if bundle.Token != "" {
+		ui.Info("Storing Discord token...")
+	}
+	if err := storage.ImportBundle(paths, bundle); err != nil {
+		return err
+	}
+	ui.Success("Backup bundle imported into profile " + paths.Profile)
+	return nil
+}
+
+func runExport(paths *config.Paths, args []string, res *result) error {
+	fs := newFlagSet("export")
+	force := fs.Bool("force", false, "replace the file if it already exists")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+	file := fs.Arg(0)
+	if _, err := os.Stat(file); err == nil && !*force {
+		return fmt.Errorf("%s already exists; supply -force to replace it", file)
+	}
+
+	bundle, err := exportBackup(paths, file)
+	if err != nil {
+		return err
+	}
+	res.Bundle = summariseBundle(file, bundle)
+	return nil
+}
+
+func runImport(paths *config.Paths, args []string, res *result) error {
+	fs := newFlagSet("import")
+	yes := fs.Bool("yes", false, "replace the existing handle and token without asking for confirmation")
+	encryption := fs.String("encryption", "", "token storage method to use instead of the one in the bundle")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+	file := fs.Arg(0)
+
+	bundle, err := openBackup(file)
+	if err != nil {
+		return err
+	}
+	res.Bundle = summariseBundle(file, bundle)
+
+	if storage.HasData(paths) && !*yes {
+		if !terminal.IsInputTerminal() {
+			return fmt.Errorf("%w; supply -yes to replace them", storage.ErrExistingData)
+		}
+		if !ui.Confirm("Replace the handle and token of profile " + paths.Profile + "? (yes/no): ") {
+			return ui.ErrCancelled
+		}
+	}
+
+	if *encryption != "" {
+		if bundle.Encryption, err = backendName(*encryption); err != nil {
+			return err
+		}
+	}
+	if bundle.Token != "" {
+		if bundle.Encryption == "" {
+			if err := resolveEncryption(paths, ""); err != nil {
+				return err
+			}
+		} else {
+			backend, _ := storage.Backend(paths, bundle.Encryption)
+			if err := backend.Available(); err != nil {
+				return fmt.Errorf("%w; supply -encryption to choose another storage method", err)
+			}
+		}
+	}
+
+	if err := importBackup(paths, bundle); err != nil {
+		return err
+	}
+	res.Handle = bundle.Handle
+	res.TokenStorage, res.TokenBackend = tokenStorage(paths)
+	return nil
+}
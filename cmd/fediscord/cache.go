@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/jimed-rand/fediscord/pkg/config"
+)
+
+const instanceCacheTTL = 6 * time.Hour
+
+type instanceCacheEntry struct {
+	Version   string    `json:"version"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+func readInstanceCache(paths *config.Paths) map[string]instanceCacheEntry {
+	entries := make(map[string]instanceCacheEntry)
+	data, err := os.ReadFile(paths.InstanceCache)
+	if err != nil {
+		return entries
+	}
+	json.Unmarshal(data, &entries)
+	return entries
+}
+
+func cachedInstance(paths *config.Paths, host string) (string, bool) {
+	entry, ok := readInstanceCache(paths)[host]
+	if !ok || time.Since(entry.CheckedAt) > instanceCacheTTL {
+		return "", false
+	}
+	return entry.Version, true
+}
+
+func cacheInstance(paths *config.Paths, host, version string) {
+	entries := readInstanceCache(paths)
+	for name, entry := range entries {
+		if time.Since(entry.CheckedAt) > instanceCacheTTL {
+			delete(entries, name)
+		}
+	}
+	entries[host] = instanceCacheEntry{Version: version, CheckedAt: time.Now().UTC()}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return
+	}
+	config.WriteFileAtomic(paths.InstanceCache, data, 0600)
+}
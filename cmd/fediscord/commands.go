@@ -0,0 +1,681 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jimed-rand/fediscord/pkg/config"
+	"github.com/jimed-rand/fediscord/pkg/discord"
+	"github.com/jimed-rand/fediscord/pkg/fediverse"
+	"github.com/jimed-rand/fediscord/pkg/storage"
+	"github.com/jimed-rand/fediscord/pkg/terminal"
+	"github.com/jimed-rand/fediscord/pkg/ui"
+)
+
+var errUsage = errors.New("invalid command usage")
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(paths *config.Paths, args []string, res *result) error
+}
+
+func commandTable() []command {
+	return []command{
+		{"setup", "setup -handle HANDLE [-token-stdin] [-encryption METHOD] [-force] [-skip-verify] [-follow-moved]", "Store a Discord token and Fediverse handle", runSetup},
+		{"url", "url", "Generate the Discord connection authorisation URL", runURL},
+		{"show", "show [-preview]", "Show the stored configuration", runShow},
+		{"check", "check [HANDLE]", "Check the instance of a handle (default: the stored handle)", runCheck},
+		{"instance", "instance [HANDLE]", "Show a report on the instance of a handle (default: the stored handle)", runInstance},
+		{"connections", "connections", "List the connections linked to the Discord account", runConnections},
+		{"unlink", "unlink [-id ID] [-yes]", "Remove a Mastodon connection from the Discord account", runUnlink},
+		{"set-visibility", "set-visibility [-id ID] [-visibility everyone|only-me] [-show-activity true|false]", "Change the visibility of a Mastodon connection", runSetVisibility},
+		{"verify", "verify", "Verify the stored Discord token with Discord", runVerify},
+		{"set-token", "set-token [-token-stdin] [-encryption METHOD] [-skip-verify]", "Replace the stored Discord token", runSetToken},
+		{"set-handle", "set-handle [-follow-moved] [-force] HANDLE", "Replace the stored Fediverse handle", runSetHandle},
+		{"encryption", "encryption -mode METHOD", "Change the token storage method", runEncryption},
+		{"profile", "profile [list | use NAME | create NAME | rename OLD NEW | delete [-yes] NAME]", "List, switch, create, rename, or delete profiles", runProfile},
+		{"export", "export [-force] FILE", "Write the profile to a passphrase-encrypted backup bundle", runExport},
+		{"import", "import [-yes] [-encryption METHOD] FILE", "Restore the profile from a backup bundle", runImport},
+		{"purge", "purge [-yes]", "Delete all stored data", runPurge},
+		{"doctor", "doctor", "Diagnose the configuration, token, instance, and Discord API", runDoctor},
+		{"audit", "audit [-repair]", "Check and repair the permissions of the configuration directory", runAudit},
+		{"version", "version", "Print the version", runVersion},
+	}
+}
+
+func runCommand(paths *config.Paths, profile string, args []string) int {
+	format, args, err := extractOutputFlag(args)
+	if err != nil {
+		ui.Error(err.Error())
+		return exitUsage
+	}
+	jsonOutput = format == outputJSON
+	if jsonOutput {
+		ui.SetOutput(os.Stderr)
+	}
+
+	if len(args) == 0 {
+		printUsage(os.Stderr)
+		return exitUsage
+	}
+
+	name := args[0]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		printUsage(os.Stdout)
+		return exitOK
+	}
+
+	for _, cmd := range commandTable() {
+		if cmd.name != name {
+			continue
+		}
+		res := &result{Command: cmd.name}
+		if profile != "" {
+			selected, err := paths.UseProfile(profile)
+			if err != nil {
+				return finish(res, err)
+			}
+			paths = selected
+		}
+		res.Profile = paths.Profile
+		if !auditExempt[cmd.name] {
+			if err := checkPermissions(paths); err != nil {
+				return finish(res, err)
+			}
+		}
+		err := cmd.run(paths, args[1:], res)
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		if errors.Is(err, errUsage) {
+			err = fmt.Errorf("%w: fediscord %s", errUsage, cmd.usage)
+		}
+		return finish(res, err)
+	}
+
+	return finish(&result{Command: name}, fmt.Errorf("%w: unknown command %q", errUsage, name))
+}
+
+func finish(res *result, err error) int {
+	if jsonOutput {
+		emitResult(res, err)
+	} else if err != nil {
+		ui.Error(err.Error())
+		if errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, "Run 'fediscord help' for a list of commands.")
+		}
+	}
+
+	return exitCode(err)
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: fediscord [--profile NAME] [--output text|json] [command] [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run without a command on a terminal to open the interactive menu.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	commands := commandTable()
+	width := 0
+	for _, cmd := range commands {
+		width = max(width, len(cmd.name))
+	}
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'fediscord <command> -h' for the flags of a command.")
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	return nil
+}
+
+func readToken(fromStdin bool) (string, error) {
+	if fromStdin {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read token from standard input: %w", err)
+		}
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			return "", errors.New("token cannot be empty")
+		}
+		return token, nil
+	}
+
+	if !terminal.IsInputTerminal() {
+		return "", errors.New("standard input is not a terminal; supply the token with -token-stdin")
+	}
+
+	token, err := ui.PromptSecret("Enter your Discord token (input hidden): ")
+	if err != nil {
+		return "", fmt.Errorf("failed to read token: %w", err)
+	}
+	if token == "" {
+		return "", errors.New("token cannot be empty")
+	}
+	return token, nil
+}
+
+func backendName(mode string) (string, error) {
+	if mode == "encrypted" {
+		return config.EncryptionAge, nil
+	}
+	names := storage.BackendNames()
+	for _, name := range names {
+		if name == mode {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q; expected encrypted, %s", storage.ErrUnknownBackend, mode, strings.Join(names, ", "))
+}
+
+func resolveEncryption(paths *config.Paths, mode string) error {
+	if mode != "" {
+		name, err := backendName(mode)
+		if err != nil {
+			return err
+		}
+		return storage.SetBackendPreference(paths, name)
+	}
+
+	if _, err := storage.ActiveBackend(paths); err == nil {
+		return nil
+	}
+	if !terminal.IsInputTerminal() {
+		return fmt.Errorf("%w; supply -encryption METHOD", storage.ErrEncryptionNotSet)
+	}
+	return askEncryptionPreference(paths)
+}
+
+func runSetup(paths *config.Paths, args []string, res *result) error {
+	fs := newFlagSet("setup")
+	handle := fs.String("handle", "", "Fediverse handle (username@instance.domain)")
+	tokenStdin := fs.Bool("token-stdin", false, "read the Discord token from standard input")
+	encryption := fs.String("encryption", "", "token storage method: encrypted, age, gpg, secret-service, pass, or plain")
+	force := fs.Bool("force", false, "store the handle even if the instance check fails")
+	skipVerify := fs.Bool("skip-verify", settings.Preferences.SkipTokenVerification, "store the token without verifying it with Discord")
+	followMoved := fs.Bool("follow-moved", settings.Preferences.FollowMovedAccounts, "store the new handle if the account has moved")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *handle == "" && fs.NArg() == 1 {
+		*handle = fs.Arg(0)
+	}
+	if *handle == "" || fs.NArg() > 1 {
+		return errUsage
+	}
+	if _, err := fediverse.ValidateHandle(*handle); err != nil {
+		return err
+	}
+
+	if err := resolveEncryption(paths, *encryption); err != nil {
+		return err
+	}
+
+	token, err := readToken(*tokenStdin)
+	if err != nil {
+		return err
+	}
+
+	if !*skipVerify {
+		user, err := checkToken(token, func(verifyErr error) error {
+			return fmt.Errorf("%w; supply -skip-verify to store the token anyway", verifyErr)
+		})
+		if err != nil {
+			return err
+		}
+		res.DiscordUser = user
+	}
+
+	if err := storeToken(paths, token); err != nil {
+		return err
+	}
+	res.TokenStorage, res.TokenBackend = tokenStorage(paths)
+
+	checked, err := applyHandle(paths, *handle, func(checkErr error) error {
+		if *force {
+			return nil
+		}
+		return fmt.Errorf("%w; supply -force to store the handle anyway", checkErr)
+	}, followMovedFlag(*followMoved))
+	res.setHandleCheck(checked)
+	if err != nil {
+		return err
+	}
+
+	ui.Success("Configuration completed for @" + checked.Handle)
+	return nil
+}
+
+func runURL(paths *config.Paths, args []string, res *result) error {
+	fs := newFlagSet("url")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errUsage
+	}
+
+	token, handle, err := loadCredentials(paths)
+	if err != nil {
+		return err
+	}
+	res.Handle = handle
+	res.Instance = fediverse.ExtractInstance(handle)
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	authURL, err := newDiscordClient(token).GenerateConnectionURL(ctx, handle)
+	if err != nil {
+		return err
+	}
+	res.AuthorizeURL = authURL
+
+	if !jsonOutput {
+		fmt.Println(authURL)
+	}
+	return nil
+}
+
+func runShow(paths *config.Paths, args []string, res *result) error {
+	fs := newFlagSet("show")
+	preview := fs.Bool("preview", false, "read the token and show its first characters")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errUsage
+	}
+
+	res.TokenStorage, res.TokenBackend = tokenStorage(paths)
+	if handle, err := storage.RetrieveHandle(paths); err == nil {
+		res.Handle = handle
+		res.Instance = fediverse.ExtractInstance(handle)
+	}
+	profiles, err := profileSummaries(paths)
+	if err != nil {
+		return err
+	}
+	res.Profiles = profiles
+
+	if !jsonOutput {
+		printConfiguration(paths)
+	}
+	if !*preview {
+		return nil
+	}
+	if jsonOutput {
+		res.TokenPreview, err = tokenPreview(paths)
+		return err
+	}
+	return printTokenPreview(paths)
+}
+
+func runCheck(paths *config.Paths, args []string, res *result) error {
+	fs := newFlagSet("check")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return errUsage
+	}
+
+	handle, err := handleArgument(paths, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	checked, err := inspectHandle(ctx, paths, handle)
+	res.setHandleCheck(checked)
+	if errors.Is(err, fediverse.ErrInvalidHandle) {
+		return err
+	}
+	compatible := err == nil
+	res.Compatible = &compatible
+	return err
+}
+
+func runVerify(paths *config.Paths, args []string, res *result) error {
+	fs := newFlagSet("verify")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errUsage
+	}
+
+	token, err := storage.RetrieveToken(paths)
+	if err != nil {
+		return err
+	}
+	res.TokenStorage, res.TokenBackend = tokenStorage(paths)
+
+	user, err := checkToken(token, func(err error) error { return err })
+	if err != nil {
+		return err
+	}
+	res.DiscordUser = user
+	return nil
+}
+
+func runConnections(paths *config.Paths, args []string, res *result) error {
+	fs := newFlagSet("connections")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errUsage
+	}
+
+	token, err := storage.RetrieveToken(paths)
+	if err != nil {
+		return err
+	}
+	handle, _ := storage.RetrieveHandle(paths)
+	res.Handle = handle
+
+	connections, err := fetchConnections(token)
+	if err != nil {
+		return err
+	}
+	res.Connections = connections
+
+	if handle != "" {
+		linked := isLinked(connections, handle)
+		res.Linked = &linked
+	}
+
+	if !jsonOutput {
+		printConnections(connections, handle)
+	}
+	return nil
+}
+
+func loadMastodonConnection(paths *config.Paths, id string, res *result) (string, *discord.Connection, error) {
+	token, err := storage.RetrieveToken(paths)
+	if err != nil {
+		return "", nil, err
+	}
+	handle, _ := storage.RetrieveHandle(paths)
+	res.Handle = handle
+
+	connections, err := fetchConnections(token)
+	if err != nil {
+		return "", nil, err
+	}
+
+	conn, err := findMastodonConnection(connections, id, handle)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, conn, nil
+}
+
+func runUnlink(paths *config.Paths, args []string, res *result) error {
+	fs := newFlagSet("unlink")
+	id := fs.String("id", "", "ID of the Mastodon connection (default: the stored handle)")
+	yes := fs.Bool("yes", false, "remove without asking for confirmation")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errUsage
+	}
+
+	token, conn, err := loadMastodonConnection(paths, *id, res)
+	if err != nil {
+		return err
+	}
+
+	if !*yes {
+		if !terminal.IsInputTerminal() {
+			return errors.New("refusing to remove the connection without confirmation; supply -yes")
+		}
+		ui.Warn("This will remove the connection " + conn.Name + " from your Discord profile.")
+		if !confirmDeletion() {
+			return ui.ErrCancelled
+		}
+	}
+
+	if err := removeConnection(token, conn); err != nil {
+		return err
+	}
+	res.Connections = []discord.Connection{*conn}
+	return nil
+}
+
+func runSetVisibility(paths *config.Paths, args []string, res *result) error {
+	fs := newFlagSet("set-visibility")
+	id := fs.String("id", "", "ID of the Mastodon connection (default: the stored handle)")
+	visibility := fs.String("visibility", "", "profile visibility: everyone or only-me")
+	showActivity := fs.String("show-activity", "", "display activity on the profile: true or false")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 || (*visibility == "" && *showActivity == "") {
+		return errUsage
+	}
+
+	var update discord.ConnectionUpdate
+	switch *visibility {
+	case "":
+	case "everyone":
+		value := discord.VisibilityEveryone
+		update.Visibility = &value
+	case "only-me":
+		value := discord.VisibilityOnlyMe
+		update.Visibility = &value
+	default:
+		return errUsage
+	}
+	if *showActivity != "" {
+		value, err := strconv.ParseBool(*showActivity)
+		if err != nil {
+			return errUsage
+		}
+		update.ShowActivity = &value
+	}
+
+	token, conn, err := loadMastodonConnection(paths, *id, res)
+	if err != nil {
+		return err
+	}
+
+	if err := updateConnection(token, conn, update); err != nil {
+		return err
+	}
+	res.Connections = []discord.Connection{*conn}
+	return nil
+}
+
+func runInstance(paths *config.Paths, args []string, res *result) error {
+	fs := newFlagSet("instance")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return errUsage
+	}
+
+	handle, err := handleArgument(paths, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	checked, info, err := fetchInstanceReport(paths, handle)
+	res.setHandleCheck(checked)
+	if err != nil {
+		return err
+	}
+	res.InstanceReport = info
+
+	if !jsonOutput {
+		printInstanceReport(checked, info)
+	}
+	return nil
+}
+
+func handleArgument(paths *config.Paths, handle string) (string, error) {
+	if handle != "" {
+		return handle, nil
+	}
+	stored, err := storage.RetrieveHandle(paths)
+	if err != nil {
+		return "", fmt.Errorf("no Fediverse handle was supplied and %w", err)
+	}
+	return stored, nil
+}
+
+func followMovedFlag(enabled bool) func(string) bool {
+	return func(moved string) bool {
+		if !enabled {
+			ui.Info("Supply -follow-moved to store @" + moved + " instead")
+		}
+		return enabled
+	}
+}
+
+func runSetToken(paths *config.Paths, args []string, res *result) error {
+	fs := newFlagSet("set-token")
+	tokenStdin := fs.Bool("token-stdin", false, "read the Discord token from standard input")
+	encryption := fs.String("encryption", "", "token storage method: encrypted, age, gpg, secret-service, pass, or plain")
+	skipVerify := fs.Bool("skip-verify", settings.Preferences.SkipTokenVerification, "store the token without verifying it with Discord")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errUsage
+	}
+
+	if err := resolveEncryption(paths, *encryption); err != nil {
+		return err
+	}
+
+	token, err := readToken(*tokenStdin)
+	if err != nil {
+		return err
+	}
+
+	if !*skipVerify {
+		user, err := checkToken(token, func(verifyErr error) error {
+			return fmt.Errorf("%w; supply -skip-verify to store the token anyway", verifyErr)
+		})
+		if err != nil {
+			return err
+		}
+		res.DiscordUser = user
+	}
+
+	if err := storeToken(paths, token); err != nil {
+		return err
+	}
+	res.TokenStorage, res.TokenBackend = tokenStorage(paths)
+	return nil
+}
+
+func runSetHandle(paths *config.Paths, args []string, res *result) error {
+	fs := newFlagSet("set-handle")
+	handle := fs.String("handle", "", "Fediverse handle (username@instance.domain)")
+	followMoved := fs.Bool("follow-moved", settings.Preferences.FollowMovedAccounts, "store the new handle if the account has moved")
+	force := fs.Bool("force", false, "store the handle even if the instance or account check fails")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *handle == "" && fs.NArg() == 1 {
+		*handle = fs.Arg(0)
+	}
+	if *handle == "" || fs.NArg() > 1 {
+		return errUsage
+	}
+
+	checked, err := applyHandle(paths, *handle, func(checkErr error) error {
+		if *force {
+			return nil
+		}
+		return fmt.Errorf("%w; supply -force to store the handle anyway", checkErr)
+	}, followMovedFlag(*followMoved))
+	res.setHandleCheck(checked)
+	if err != nil {
+		return err
+	}
+
+	ui.Success("Fediverse handle updated to: @" + checked.Handle)
+	return nil
+}
+
+func runEncryption(paths *config.Paths, args []string, res *result) error {
+	fs := newFlagSet("encryption")
+	mode := fs.String("mode", "", "token storage method: encrypted, age, gpg, secret-service, pass, or plain")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *mode == "" || fs.NArg() != 0 {
+		return errUsage
+	}
+
+	name, err := backendName(*mode)
+	if err != nil {
+		return err
+	}
+	err = migrateToken(paths, name)
+	res.TokenStorage, res.TokenBackend = tokenStorage(paths)
+	return err
+}
+
+func runPurge(paths *config.Paths, args []string, res *result) error {
+	fs := newFlagSet("purge")
+	yes := fs.Bool("yes", false, "delete without asking for confirmation")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errUsage
+	}
+
+	if !*yes {
+		if !terminal.IsInputTerminal() {
+			return errors.New("refusing to delete data without confirmation; supply -yes")
+		}
+		if !confirmDeletion() {
+			return ui.ErrCancelled
+		}
+	}
+
+	report, err := purgeData(paths)
+	res.Purge = report
+	return err
+}
+
+func runVersion(paths *config.Paths, args []string, res *result) error {
+	res.Version = version
+	if !jsonOutput {
+		fmt.Println("fediscord " + version)
+	}
+	return nil
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/jimed-rand/fediscord/pkg/audit"
+	"github.com/jimed-rand/fediscord/pkg/config"
+	"github.com/jimed-rand/fediscord/pkg/discord"
+	"github.com/jimed-rand/fediscord/pkg/fediverse"
+	"github.com/jimed-rand/fediscord/pkg/storage"
+	"github.com/jimed-rand/fediscord/pkg/ui"
+)
+
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+var errChecksFailed = errors.New("one or more diagnostic checks failed")
+
+type checkResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	Hint   string `json:"hint,omitempty"`
+}
+
+type diagnosis struct {
+	ctx     context.Context
+	paths   *config.Paths
+	results []checkResult
+	token   string
+	backend storage.SecretStore
+	handle  fediverse.Handle
+	host    string
+}
+
+func (d *diagnosis) add(name, status, detail, hint string) {
+	detail = strings.Join(strings.Fields(detail), " ")
+	d.results = append(d.results, checkResult{Name: name, Status: status, Detail: detail, Hint: hint})
+}
+
+func diagnose(paths *config.Paths) ([]checkResult, error) {
+	ctx, stop := interruptContext()
+	defer stop()
+
+	d := &diagnosis{ctx: ctx, paths: paths}
+	d.checkPermissions()
+	d.checkToken()
+	d.checkGPG()
+	d.checkHandle()
+	d.checkInstance()
+	d.checkDiscord()
+	if err := ctx.Err(); err != nil {
+		return d.results, err
+	}
+	return d.results, nil
+}
+
+func (d *diagnosis) checkPermissions() {
+	const name = "Configuration permissions"
+	findings, err := audit.Check(d.paths.Dir)
+	switch {
+	case err != nil:
+		d.add(name, checkWarn, "the permissions could not be checked: "+err.Error(), "")
+	case len(findings) == 0:
+		d.add(name, checkPass, d.paths.Dir+" is private", "")
+	case audit.HasCritical(findings):
+		d.add(name, checkFail, describeFindings(findings), "Run 'fediscord audit -repair'; files owned by another user must be given back with chown.")
+	default:
+		d.add(name, checkWarn, describeFindings(findings), "Run 'fediscord audit -repair'.")
+	}
+}
+
+func describeFindings(findings []audit.Finding) string {
+	detail := findings[0].Path + " " + findings[0].Problem
+	if len(findings) > 1 {
+		detail += fmt.Sprintf(" (and %d more)", len(findings)-1)
+	}
+	return detail
+}
+
+func (d *diagnosis) checkToken() {
+	backend, err := storage.LocateToken(d.paths)
+	if err != nil {
+		d.add("Stored token", checkFail, err.Error(), "Store a token with Option 1 or Option 4, or 'fediscord set-token'.")
+		d.add("Token decryption", checkSkip, "no token is stored", "")
+		return
+	}
+	d.backend = backend
+
+	preferred, _ := storage.BackendPreference(d.paths)
+	switch {
+	case preferred != "" && preferred != backend.Name():
+		d.add("Stored token", checkWarn, describeBackend(backend)+", although "+preferred+" is selected",
+			"Choose the storage method again with Option 6 to move the token.")
+	case !backend.Encrypted():
+		d.add("Stored token", checkWarn, "kept unencrypted in "+backend.Location(),
+			"Encrypt the token with Option 6 or 'fediscord encryption -mode age'.")
+	default:
+		d.add("Stored token", checkPass, describeBackend(backend), "")
+	}
+
+	token, err := backend.Retrieve()
+	switch {
+	case err == nil:
+		d.token = token
+		d.add("Token decryption", checkPass, "the token was read successfully", "")
+	case errors.Is(err, storage.ErrNoPassphrase), errors.Is(err, storage.ErrPromptDismissed), errors.Is(err, ui.ErrCancelled):
+		d.add("Token decryption", checkWarn, "not checked: "+err.Error(), "Run the check on a terminal or set "+passphraseEnv+".")
+	case errors.Is(err, storage.ErrDecryption):
+		d.add("Token decryption", checkFail, err.Error(),
+			"Enter the passphrase used when the token was stored; if it has been lost, store the token again with Option 4.")
+	case errors.Is(err, storage.ErrGPG), errors.Is(err, storage.ErrGPGUnavailable):
+		d.add("Token decryption", checkFail, err.Error(),
+			"Check that GPG and its agent work, or move the token to another storage method with Option 6.")
+	case errors.Is(err, storage.ErrBackendUnavailable), errors.Is(err, storage.ErrBackend):
+		d.add("Token decryption", checkFail, err.Error(),
+			"Check that the keyring or the password store is installed and unlocked.")
+	default:
+		d.add("Token decryption", checkFail, err.Error(), "Store the token again with Option 4.")
+	}
+}
+
+func describeBackend(backend storage.SecretStore) string {
+	return backend.Description() + " (" + backend.Name() + ")"
+}
+
+func (d *diagnosis) needsGPG() bool {
+	if d.backend != nil && (d.backend.Name() == config.EncryptionGPG || d.backend.Name() == config.EncryptionPass) {
+		return true
+	}
+	preferred, _ := storage.BackendPreference(d.paths)
+	return preferred == config.EncryptionGPG || preferred == config.EncryptionPass
+}
+
+func (d *diagnosis) checkGPG() {
+	failure := checkWarn
+	if d.needsGPG() {
+		failure = checkFail
+	}
+
+	version, err := storage.GPGVersion()
+	if err != nil {
+		if failure == checkFail {
+			d.add("GPG", checkFail, "GPG is not available: "+err.Error(), "Install GnuPG, or move the token to another storage method with Option 6.")
+		} else {
+			d.add("GPG", checkSkip, "not installed; only the gpg and pass storage methods require it", "")
+		}
+		d.add("GPG agent", checkSkip, "GPG is not available", "")
+		return
+	}
+	d.add("GPG", checkPass, version, "")
+
+	if err := storage.CheckGPGAgent(); err != nil {
+		d.add("GPG agent", failure, err.Error(), "Start the agent with 'gpgconf --launch gpg-agent' and check that a pinentry program is installed.")
+		return
+	}
+	d.add("GPG agent", checkPass, "the agent is running", "")
+}
+
+func (d *diagnosis) checkHandle() {
+	stored, err := storage.RetrieveHandle(d.paths)
+	if err != nil {
+		d.add("Fediverse handle", checkFail, err.Error(), "Store a handle with Option 5 or 'fediscord set-handle'.")
+		return
+	}
+	parsed, err := fediverse.ParseHandle(stored)
+	if err != nil {
+		d.add("Fediverse handle", checkFail, err.Error(), "Store a handle of the form username@instance.domain with Option 5.")
+		return
+	}
+	d.handle = parsed
+	d.add("Fediverse handle", checkPass, "@"+parsed.Display(), "")
+}
+
+func (d *diagnosis) checkInstance() {
+	if d.handle.Domain == "" {
+		d.add("WebFinger", checkSkip, "no valid handle is stored", "")
+		d.add("Instance reachability", checkSkip, "no valid handle is stored", "")
+		d.add("Instance compatibility", checkSkip, "no valid handle is stored", "")
+		return
+	}
+
+	client := newFediverseClient()
+	d.host = d.handle.APIHost
+	account, err := client.Resolve(d.ctx, d.handle)
+	switch {
+	case err == nil:
+		d.host = account.Host
+		d.add("WebFinger", checkPass, "@"+account.Handle+" is served by "+account.Host, "")
+	case errors.Is(err, fediverse.ErrAccountNotFound):
+		d.add("WebFinger", checkFail, err.Error(), "Check the spelling of the handle, and whether the account has moved or been deleted.")
+	default:
+		d.add("WebFinger", checkWarn, err.Error(), "Check that "+d.handle.Domain+" resolves in DNS; "+d.host+" is assumed to be the instance.")
+	}
+
+	version, err := client.CheckMastodonAPISupport(d.ctx, d.host)
+	switch {
+	case errors.Is(err, fediverse.ErrInstanceUnreachable):
+		d.add("Instance reachability", checkFail, err.Error(),
+			"Check DNS resolution of and network access to "+d.host+", or whether the instance is down.")
+		d.add("Instance compatibility", checkSkip, "the instance could not be reached", "")
+		return
+	case err == nil:
+		d.add("Instance reachability", checkPass, d.host+" responded", "")
+		d.add("Instance compatibility", checkPass, version, "")
+	case errors.Is(err, fediverse.ErrInstanceIncompatible):
+		d.add("Instance reachability", checkPass, d.host+" responded", "")
+		d.add("Instance compatibility", checkFail, err.Error(),
+			"Discord can only link accounts on platforms implementing the Mastodon API; see Platform Compatibility in the README.")
+	default:
+		d.add("Instance reachability", checkWarn, err.Error(), "Run 'fediscord check' for details.")
+		d.add("Instance compatibility", checkSkip, "the instance could not be checked", "")
+	}
+}
+
+func (d *diagnosis) checkDiscord() {
+	host := settings.API.Discord
+	if host == "" {
+		host = discord.DefaultBaseURL
+	}
+	if parsed, err := url.Parse(host); err == nil && parsed.Host != "" {
+		host = parsed.Host
+	}
+
+	err := newDiscordClient("").Ping(d.ctx)
+	switch {
+	case err == nil:
+		d.add("Discord API reachability", checkPass, host+" responded", "")
+	case errors.Is(err, discord.ErrRateLimited), errors.Is(err, discord.ErrUnavailable):
+		d.add("Discord API reachability", checkWarn, err.Error(), "Wait a few minutes and retry.")
+	default:
+		d.add("Discord API reachability", checkFail, err.Error(),
+			"Check network access to "+host+"; a firewall, proxy, or DNS filter may block it.")
+		d.add("Discord token validity", checkSkip, "the Discord API could not be reached", "")
+		return
+	}
+
+	if d.token == "" {
+		d.add("Discord token validity", checkSkip, "no readable token is stored", "")
+		return
+	}
+	user, err := newDiscordClient(d.token).CurrentUser(d.ctx)
+	switch {
+	case err == nil:
+		d.add("Discord token validity", checkPass, "the token belongs to "+user.Tag(), "")
+	case errors.Is(err, discord.ErrUnauthorized):
+		d.add("Discord token validity", checkFail, err.Error(),
+			"The token has been invalidated, typically by a password change; retrieve a new token and store it with Option 4.")
+	default:
+		d.add("Discord token validity", checkWarn, "the token could not be verified: "+err.Error(), "Retry later with Option 8.")
+	}
+}
+
+func printChecks(results []checkResult) {
+	width := len("Check")
+	for _, result := range results {
+		if len(result.Name) > width {
+			width = len(result.Name)
+		}
+	}
+
+	ui.Info(fmt.Sprintf("%-6s  %-*s  %s", "Status", width, "Check", "Result"))
+	ui.Separator()
+	counts := map[string]int{}
+	for _, result := range results {
+		counts[result.Status]++
+		ui.Info(fmt.Sprintf("%-6s  %-*s  %s", strings.ToUpper(result.Status), width, result.Name, result.Detail))
+	}
+
+	hinted := false
+	for _, result := range results {
+		if result.Hint == "" || result.Status == checkPass {
+			continue
+		}
+		if !hinted {
+			fmt.Println()
+			ui.Info("Remediation:")
+			hinted = true
+		}
+		ui.Info("  " + result.Name + ": " + result.Hint)
+	}
+
+	fmt.Println()
+	summary := fmt.Sprintf("%d passed, %d warning(s), %d failed, %d skipped",
+		counts[checkPass], counts[checkWarn], counts[checkFail], counts[checkSkip])
+	switch {
+	case counts[checkFail] > 0:
+		ui.Error(summary)
+	case counts[checkWarn] > 0:
+		ui.Warn(summary)
+	default:
+		ui.Success(summary)
+	}
+}
+
+func checksFailed(results []checkResult) bool {
+	for _, result := range results {
+		if result.Status == checkFail {
+			return true
+		}
+	}
+	return false
+}
+
+func runDiagnostics(paths *config.Paths) {
+	ui.PrintHeader("Diagnostics")
+	ui.Info("Checking the configuration, token, GPG, Fediverse instance, and Discord API...")
+	fmt.Println()
+
+	results, err := diagnose(paths)
+	printChecks(results)
+	if errors.Is(err, context.Canceled) {
+		ui.Info("Cancelled")
+	}
+
+	fmt.Println()
+	ui.PressEnter()
+}
+
+func runDoctor(paths *config.Paths, args []string, res *result) error {
+	fs := newFlagSet("doctor")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errUsage
+	}
+
+	results, err := diagnose(paths)
+	res.Checks = results
+	if !jsonOutput {
+		printChecks(results)
+	}
+	if err != nil {
+		return err
+	}
+	if checksFailed(results) {
+		return errChecksFailed
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jimed-rand/fediscord/pkg/audit"
+	"github.com/jimed-rand/fediscord/pkg/config"
+	"github.com/jimed-rand/fediscord/pkg/discord"
+	"github.com/jimed-rand/fediscord/pkg/fediverse"
+	"github.com/jimed-rand/fediscord/pkg/storage"
+	"github.com/jimed-rand/fediscord/pkg/ui"
+)
+
+const (
+	exitOK                   = 0
+	exitFailure              = 1
+	exitUsage                = 2
+	exitNoToken              = 3
+	exitNoHandle             = 4
+	exitInvalidHandle        = 5
+	exitInstanceUnreachable  = 6
+	exitInstanceIncompatible = 7
+	exitDiscordUnauthorized  = 8
+	exitDiscordRateLimited   = 9
+	exitGPGFailure           = 10
+	exitCancelled            = 11
+	exitDiscordForbidden     = 12
+	exitDiscordBadRequest    = 13
+	exitDiscordUnavailable   = 14
+	exitAccountNotFound      = 15
+	exitProfileNotFound      = 16
+	exitDecryptionFailed     = 17
+	exitSecretStoreFailure   = 18
+	exitBundleInvalid        = 19
+	exitExistingData         = 20
+	exitLocked               = 21
+	exitPurgeIncomplete      = 22
+	exitInsecurePermissions  = 23
+	exitChecksFailed         = 24
+	exitProfileExists        = 25
+	exitProfileInUse         = 26
+)
+
+type failureClass struct {
+	target error
+	exit   int
+	code   string
+}
+
+var failureClasses = []failureClass{
+	{errUsage, exitUsage, "usage"},
+	{ui.ErrCancelled, exitCancelled, "cancelled"},
+	{context.Canceled, exitCancelled, "cancelled"},
+	{storage.ErrPromptDismissed, exitCancelled, "cancelled"},
+	{storage.ErrEncryptionNotSet, exitUsage, "usage"},
+	{storage.ErrTokenNotFound, exitNoToken, "no_token"},
+	{storage.ErrHandleNotFound, exitNoHandle, "no_handle"},
+	{config.ErrProfileNotFound, exitProfileNotFound, "profile_not_found"},
+	{config.ErrInvalidProfile, exitUsage, "usage"},
+	{config.ErrProfileExists, exitProfileExists, "profile_exists"},
+	{config.ErrProfileInUse, exitProfileInUse, "profile_in_use"},
+	{config.ErrLocked, exitLocked, "locked"},
+	{audit.ErrInsecure, exitInsecurePermissions, "insecure_permissions"},
+	{fediverse.ErrInvalidHandle, exitInvalidHandle, "invalid_handle"},
+	{fediverse.ErrInstanceUnreachable, exitInstanceUnreachable, "instance_unreachable"},
+	{fediverse.ErrInstanceIncompatible, exitInstanceIncompatible, "instance_incompatible"},
+	{fediverse.ErrAccountNotFound, exitAccountNotFound, "account_not_found"},
+	{discord.ErrUnauthorized, exitDiscordUnauthorized, "discord_unauthorized"},
+	{discord.ErrRateLimited, exitDiscordRateLimited, "discord_rate_limited"},
+	{discord.ErrForbidden, exitDiscordForbidden, "discord_forbidden"},
+	{discord.ErrBadRequest, exitDiscordBadRequest, "discord_bad_request"},
+	{discord.ErrUnavailable, exitDiscordUnavailable, "discord_unavailable"},
+	{storage.ErrGPG, exitGPGFailure, "gpg_failure"},
+	{storage.ErrGPGUnavailable, exitGPGFailure, "gpg_failure"},
+	{storage.ErrDecryption, exitDecryptionFailed, "decryption_failed"},
+	{storage.ErrNoPassphrase, exitDecryptionFailed, "no_passphrase"},
+	{storage.ErrUnknownBackend, exitUsage, "usage"},
+	{storage.ErrBackendUnavailable, exitSecretStoreFailure, "secret_store_unavailable"},
+	{storage.ErrBackend, exitSecretStoreFailure, "secret_store_failure"},
+	{storage.ErrBundle, exitBundleInvalid, "bundle_invalid"},
+	{storage.ErrExistingData, exitExistingData, "existing_data"},
+	{storage.ErrPurgeIncomplete, exitPurgeIncomplete, "purge_incomplete"},
+	{errChecksFailed, exitChecksFailed, "checks_failed"},
+}
+
+func classify(err error) failureClass {
+	for _, class := range failureClasses {
+		if errors.Is(err, class.target) {
+			return class
+		}
+	}
+	return failureClass{err, exitFailure, "error"}
+}
+
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	return classify(err).exit
+}
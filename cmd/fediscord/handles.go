@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jimed-rand/fediscord/pkg/config"
+	"github.com/jimed-rand/fediscord/pkg/fediverse"
+	"github.com/jimed-rand/fediscord/pkg/storage"
+	"github.com/jimed-rand/fediscord/pkg/ui"
+)
+
+type handleCheck struct {
+	Handle  string
+	Host    string
+	Version string
+	Account *fediverse.Profile
+}
+
+func newFediverseClient() *fediverse.Client {
+	client := fediverse.NewClient()
+	client.UserAgent = fediverse.DefaultUserAgent + "/" + version
+	return client
+}
+
+func inspectHandle(ctx context.Context, paths *config.Paths, input string) (handleCheck, error) {
+	parsed, err := fediverse.ParseHandle(input)
+	if err != nil {
+		return handleCheck{}, err
+	}
+
+	client := newFediverseClient()
+	if fediverse.IsProfileURL(input) {
+		resolved, err := client.ResolveProfileURL(ctx, input)
+		switch {
+		case err == nil:
+			parsed = resolved
+			ui.Info("  Profile URL belongs to @" + parsed.String())
+		case errors.Is(err, context.Canceled):
+			return handleCheck{}, err
+		default:
+			ui.Warn(err.Error())
+			ui.Warn("Using @" + parsed.String() + " as read from the profile URL")
+		}
+	}
+
+	if display := parsed.Display(); display != parsed.String() {
+		ui.Info("  Handle: @" + display + " (@" + parsed.String() + ")")
+	}
+
+	checked := handleCheck{Handle: parsed.String(), Host: parsed.APIHost}
+
+	account, err := client.Resolve(ctx, parsed)
+	switch {
+	case err == nil:
+		checked.Handle = account.Handle
+		checked.Host = account.Host
+		if account.Handle != parsed.String() {
+			ui.Info("  Canonical account: @" + account.Handle)
+		}
+	case errors.Is(err, fediverse.ErrAccountNotFound), errors.Is(err, context.Canceled):
+		return checked, err
+	default:
+		ui.Warn(err.Error())
+		ui.Warn("Falling back to " + checked.Host + " as the instance host")
+	}
+
+	ui.Success("Instance: " + checked.Host)
+
+	if version, ok := cachedInstance(paths, checked.Host); ok {
+		checked.Version = version
+		ui.Success("Instance is running: " + checked.Version + " (cached)")
+	} else {
+		checked.Version, err = client.CheckMastodonAPISupport(ctx, checked.Host)
+		if err != nil {
+			return checked, err
+		}
+		cacheInstance(paths, checked.Host, checked.Version)
+		ui.Success("Instance is running: " + checked.Version)
+	}
+	ui.Success("Instance appears to support Mastodon API")
+
+	profile, err := client.LookupAccount(ctx, checked.Host, checked.Handle)
+	switch {
+	case err == nil:
+		checked.Account = profile
+		printProfile(profile, fediverse.ExtractInstance(checked.Handle))
+	case errors.Is(err, fediverse.ErrAccountNotFound), errors.Is(err, context.Canceled):
+		return checked, err
+	default:
+		ui.Warn(err.Error())
+		ui.Warn("The account could not be confirmed on " + checked.Host)
+	}
+	return checked, nil
+}
+
+func printProfile(profile *fediverse.Profile, domain string) {
+	ui.Success("Account found: " + profile.Name() + " (@" + profile.Handle(domain) + ")")
+	if profile.Avatar != "" {
+		ui.Info("  Avatar:    " + profile.Avatar)
+	}
+	ui.Info(fmt.Sprintf("  Followers: %d", profile.FollowersCount))
+	if profile.Moved != nil {
+		ui.Warn("This account has moved to @" + profile.Moved.Handle(domain))
+	}
+}
+
+func movedHandle(checked handleCheck) string {
+	if checked.Account == nil || checked.Account.Moved == nil {
+		return ""
+	}
+	return checked.Account.Moved.Handle(fediverse.ExtractInstance(checked.Handle))
+}
+
+func applyHandle(paths *config.Paths, input string, proceed func(error) error, follow func(moved string) bool) (handleCheck, error) {
+	ctx, stop := interruptContext()
+	defer stop()
+
+	checked, err := inspectHandle(ctx, paths, input)
+	if moved := movedHandle(checked); err == nil && moved != "" && follow(moved) {
+		ui.Info("Switching to @" + moved)
+		checked, err = inspectHandle(ctx, paths, moved)
+	}
+	switch {
+	case errors.Is(err, fediverse.ErrInvalidHandle), errors.Is(err, context.Canceled):
+		return checked, err
+	case err != nil:
+		ui.Warn(err.Error())
+		if err := proceed(err); err != nil {
+			return checked, err
+		}
+	}
+
+	if err := storage.StoreHandle(paths, checked.Handle); err != nil {
+		return checked, fmt.Errorf("failed to save handle: %w", err)
+	}
+	return checked, nil
+}
+
+func fetchInstanceReport(paths *config.Paths, input string) (handleCheck, *fediverse.InstanceInfo, error) {
+	ctx, stop := interruptContext()
+	defer stop()
+
+	checked, err := inspectHandle(ctx, paths, input)
+	if err != nil && (checked.Host == "" || errors.Is(err, context.Canceled)) {
+		return checked, nil, err
+	}
+	if err != nil {
+		ui.Warn(err.Error())
+	}
+
+	info, err := newFediverseClient().Instance(ctx, checked.Host)
+	if err != nil {
+		return checked, nil, err
+	}
+	return checked, info, nil
+}
+
+func printInstanceReport(checked handleCheck, info *fediverse.InstanceInfo) {
+	fmt.Println()
+	ui.Separator()
+	ui.Info("Instance report for @" + checked.Handle)
+	ui.Separator()
+	ui.Info("  Title:          " + info.Title)
+	ui.Info("  Domain:         " + info.Domain)
+	ui.Info("  API host:       " + checked.Host)
+	ui.Info("  Software:       " + checked.Version)
+	ui.Info(fmt.Sprintf("  API version:    v%d (server version %s)", info.APIVersion, info.Version))
+	if info.SourceURL != "" {
+		ui.Info("  Source code:    " + info.SourceURL)
+	}
+
+	registrations := "closed"
+	if info.Registrations.Enabled {
+		registrations = "open"
+		if info.Registrations.ApprovalRequired {
+			registrations = "open (approval required)"
+		}
+	}
+	ui.Info("  Registrations:  " + registrations)
+
+	if info.Contact.Email != "" {
+		ui.Info("  Contact e-mail: " + info.Contact.Email)
+	}
+	if account := info.Contact.Account; account != nil {
+		ui.Info("  Contact:        @" + account.Acct + " (" + account.URL + ")")
+	}
+
+	limits := info.Configuration
+	ui.Info("  Limits:")
+	ui.Info("    Characters per post:  " + formatLimit(int64(limits.Statuses.MaxCharacters), formatCount))
+	ui.Info("    Attachments per post: " + formatLimit(int64(limits.Statuses.MaxMediaAttachments), formatCount))
+	ui.Info("    Poll options:         " + formatLimit(int64(limits.Polls.MaxOptions), formatCount))
+	ui.Info("    Image size:           " + formatLimit(limits.MediaAttachments.ImageSizeLimit, formatBytes))
+	ui.Info("    Video size:           " + formatLimit(limits.MediaAttachments.VideoSizeLimit, formatBytes))
+	ui.Separator()
+}
+
+func formatLimit(value int64, format func(int64) string) string {
+	if value <= 0 {
+		return "not reported"
+	}
+	return format(value)
+}
+
+func formatCount(value int64) string {
+	return fmt.Sprint(value)
+}
+
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
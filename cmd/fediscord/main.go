@@ -5,27 +5,84 @@ import (
 	"os"
 	"time"
 
-	"github.com/jimed-rand/fediscord/internal/config"
-	"github.com/jimed-rand/fediscord/internal/ui"
+	"github.com/jimed-rand/fediscord/pkg/config"
+	"github.com/jimed-rand/fediscord/pkg/storage"
+	"github.com/jimed-rand/fediscord/pkg/terminal"
+	"github.com/jimed-rand/fediscord/pkg/ui"
 )
 
+var version = "dev"
+
+const passphraseEnv = "FEDISCORD_PASSPHRASE"
+
+var settings = config.Default()
+
 func main() {
 	paths, err := config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to load configuration paths: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 
-	if err := paths.Initialize(); err != nil {
+	if err := paths.Initialise(); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to initialize config directory: %v\n", err)
 		os.Exit(1)
 	}
 
+	if paths.MigratedFrom != "" {
+		fmt.Fprintf(os.Stderr, "Notice: the configuration has been moved from %s to %s\n", paths.MigratedFrom, paths.Dir)
+	}
+	for _, legacy := range paths.Unmigrated {
+		fmt.Fprintf(os.Stderr, "Notice: %s was not moved into profile %s, which already holds a token; delete it once it is no longer needed\n", legacy, paths.Profile)
+	}
+
+	settings, err = paths.ReadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	storage.SetPassphraseSource(promptPassphrase)
+
+	profile, args, err := extractGlobalFlag(os.Args[1:], "profile")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitUsage)
+	}
+
+	if len(args) > 0 {
+		os.Exit(runCommand(paths, profile, args))
+	}
+
+	if !terminal.IsTerminal() || !terminal.IsInputTerminal() {
+		printUsage(os.Stderr)
+		os.Exit(exitUsage)
+	}
+
+	if profile != "" {
+		paths, err = paths.UseProfile(profile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to select profile: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+	}
+
+	if err := checkPermissions(paths); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitCode(err))
+	}
+
+	runMenu(paths)
+}
+
+func runMenu(paths *config.Paths) {
 	for {
 		ui.PrintHeader("Main Menu")
+		ui.Info("Active profile: " + paths.Profile)
+		fmt.Println()
 		ui.PrintMenu()
 
-		choice := ui.Prompt("Select an option (1-8): ")
+		choice := ui.Prompt("Select an option (1-15): ")
 
 		switch choice {
 		case "1":
@@ -43,13 +100,27 @@ func main() {
 		case "7":
 			deleteAllData(paths)
 		case "8":
+			verifyDiscordToken(paths)
+		case "9":
+			listDiscordConnections(paths)
+		case "10":
+			manageMastodonConnection(paths)
+		case "11":
+			showInstanceReport(paths)
+		case "12":
+			manageProfiles(paths)
+		case "13":
+			manageBackup(paths)
+		case "14":
+			runDiagnostics(paths)
+		case "15":
 			fmt.Print("\033[H\033[2J")
 			ui.Separator()
 			ui.Info("Thank you for using Fediverse to Discord Connection Tool!")
 			ui.Separator()
 			os.Exit(0)
 		default:
-			ui.Error("Invalid option. Please choose 1-8.")
+			ui.Error("Invalid option. Please choose 1-15.")
 			time.Sleep(2 * time.Second)
 		}
 	}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jimed-rand/fediscord/pkg/audit"
+	"github.com/jimed-rand/fediscord/pkg/config"
+	"github.com/jimed-rand/fediscord/pkg/discord"
+	"github.com/jimed-rand/fediscord/pkg/fediverse"
+	"github.com/jimed-rand/fediscord/pkg/storage"
+	"github.com/jimed-rand/fediscord/pkg/ui"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+var jsonOutput bool
+
+type result struct {
+	Command         string                  `json:"command"`
+	OK              bool                    `json:"ok"`
+	Profile         string                  `json:"profile,omitempty"`
+	Handle          string                  `json:"handle,omitempty"`
+	Instance        string                  `json:"instance,omitempty"`
+	InstanceVersion string                  `json:"instance_version,omitempty"`
+	Compatible      *bool                   `json:"compatible,omitempty"`
+	Account         *fediverse.Profile      `json:"account,omitempty"`
+	TokenStorage    string                  `json:"token_storage,omitempty"`
+	TokenBackend    string                  `json:"token_backend,omitempty"`
+	TokenPreview    string                  `json:"token_preview,omitempty"`
+	AuthorizeURL    string                  `json:"authorize_url,omitempty"`
+	DiscordUser     *discord.User           `json:"discord_user,omitempty"`
+	Connections     []discord.Connection    `json:"connections,omitempty"`
+	Linked          *bool                   `json:"linked,omitempty"`
+	InstanceReport  *fediverse.InstanceInfo `json:"instance_report,omitempty"`
+	Profiles        []profileSummary        `json:"profiles,omitempty"`
+	Bundle          *bundleSummary          `json:"bundle,omitempty"`
+	Purge           *storage.PurgeReport    `json:"purge,omitempty"`
+	Permissions     []audit.Finding         `json:"permissions,omitempty"`
+	Checks          []checkResult           `json:"checks,omitempty"`
+	Version         string                  `json:"version,omitempty"`
+	Error           *resultError            `json:"error,omitempty"`
+}
+
+type resultError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func extractOutputFlag(args []string) (string, []string, error) {
+	format, rest, err := extractGlobalFlag(args, "output")
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch format {
+	case "", outputText:
+		return outputText, rest, nil
+	case outputJSON:
+		return format, rest, nil
+	default:
+		return "", nil, fmt.Errorf("unknown output format %q; expected text or json", format)
+	}
+}
+
+func extractGlobalFlag(args []string, flagName string) (string, []string, error) {
+	value := ""
+	rest := make([]string, 0, len(args))
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, argValue, hasValue := strings.Cut(arg, "=")
+		if name != "-"+flagName && name != "--"+flagName {
+			rest = append(rest, arg)
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return "", nil, errors.New("flag needs an argument: -" + flagName)
+			}
+			i++
+			argValue = args[i]
+		}
+		value = argValue
+	}
+	return value, rest, nil
+}
+
+func (res *result) setHandleCheck(checked handleCheck) {
+	res.Handle = checked.Handle
+	res.Instance = checked.Host
+	res.InstanceVersion = checked.Version
+	res.Account = checked.Account
+}
+
+func tokenStorage(paths *config.Paths) (string, string) {
+	backend, err := storage.LocateToken(paths)
+	switch {
+	case err != nil:
+		return "none", ""
+	case backend.Encrypted():
+		return "encrypted", backend.Name()
+	default:
+		return "plain", backend.Name()
+	}
+}
+
+func emitResult(res *result, err error) {
+	res.OK = err == nil
+	if err != nil {
+		res.Error = &resultError{Code: classify(err).code, Message: err.Error()}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if encErr := encoder.Encode(res); encErr != nil {
+		ui.Error("failed to encode JSON output: " + encErr.Error())
+	}
+}
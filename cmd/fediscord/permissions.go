@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/jimed-rand/fediscord/pkg/audit"
+	"github.com/jimed-rand/fediscord/pkg/config"
+	"github.com/jimed-rand/fediscord/pkg/terminal"
+	"github.com/jimed-rand/fediscord/pkg/ui"
+)
+
+var auditExempt = map[string]bool{
+	"audit":   true,
+	"doctor":  true,
+	"purge":   true,
+	"version": true,
+}
+
+func checkPermissions(paths *config.Paths) error {
+	findings, err := audit.Check(paths.Dir)
+	if err != nil {
+		ui.Warn("The permissions of the configuration directory could not be checked: " + err.Error())
+		return nil
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+
+	printFindings(findings)
+	if !jsonOutput && terminal.IsInputTerminal() && ui.Confirm("Repair the permissions now? (yes/no): ") {
+		_, err := repairPermissions(findings)
+		return err
+	}
+	if audit.HasCritical(findings) {
+		return fmt.Errorf("%w; run 'fediscord audit -repair' to repair them", audit.ErrInsecure)
+	}
+	return nil
+}
+
+func printFindings(findings []audit.Finding) {
+	ui.Warn("Insecure permissions were found in the configuration directory:")
+	for _, finding := range findings {
+		line := "  " + finding.Path + " " + finding.Problem
+		if !finding.Repairable {
+			line += " (cannot be repaired automatically)"
+		}
+		if finding.Critical {
+			ui.Error(line)
+		} else {
+			ui.Warn(line)
+		}
+	}
+}
+
+func repairPermissions(findings []audit.Finding) ([]audit.Finding, error) {
+	remaining, err := audit.Repair(findings)
+	if err != nil {
+		return remaining, fmt.Errorf("the permissions could not be repaired: %w", err)
+	}
+	if len(remaining) < len(findings) {
+		ui.Success("Permissions repaired")
+	}
+	if audit.HasCritical(remaining) {
+		return remaining, fmt.Errorf("%w; the ownership must be corrected manually", audit.ErrInsecure)
+	}
+	return remaining, nil
+}
+
+func runAudit(paths *config.Paths, args []string, res *result) error {
+	fs := newFlagSet("audit")
+	repair := fs.Bool("repair", false, "restrict the permissions of the files that other users can access")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errUsage
+	}
+
+	findings, err := audit.Check(paths.Dir)
+	if err != nil {
+		return err
+	}
+	res.Permissions = findings
+	if len(findings) == 0 {
+		ui.Success("The configuration directory and credential files are private")
+		return nil
+	}
+
+	printFindings(findings)
+	if *repair {
+		res.Permissions, err = repairPermissions(findings)
+		return err
+	}
+	if audit.HasCritical(findings) {
+		return fmt.Errorf("%w; supply -repair to repair them", audit.ErrInsecure)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jimed-rand/fediscord/pkg/config"
+	"github.com/jimed-rand/fediscord/pkg/history"
+	"github.com/jimed-rand/fediscord/pkg/storage"
+	"github.com/jimed-rand/fediscord/pkg/terminal"
+	"github.com/jimed-rand/fediscord/pkg/ui"
+)
+
+type profileSummary struct {
+	Name         string `json:"name"`
+	Active       bool   `json:"active"`
+	Handle       string `json:"handle,omitempty"`
+	TokenStorage string `json:"token_storage"`
+	TokenBackend string `json:"token_backend,omitempty"`
+}
+
+func profileSummaries(paths *config.Paths) ([]profileSummary, error) {
+	names, err := paths.Profiles()
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]profileSummary, 0, len(names))
+	for _, name := range names {
+		profile := paths.ForProfile(name)
+		handle, _ := storage.RetrieveHandle(profile)
+		kind, backend := tokenStorage(profile)
+		summaries = append(summaries, profileSummary{
+			Name:         name,
+			Active:       name == paths.Profile,
+			Handle:       handle,
+			TokenStorage: kind,
+			TokenBackend: backend,
+		})
+	}
+	return summaries, nil
+}
+
+func printProfiles(paths *config.Paths) {
+	summaries, err := profileSummaries(paths)
+	if err != nil {
+		ui.Error("Failed to list profiles: " + err.Error())
+		return
+	}
+
+	ui.Info("Profiles:")
+	for _, summary := range summaries {
+		marker := " "
+		if summary.Active {
+			marker = "*"
+		}
+		handle := "[no handle]"
+		if summary.Handle != "" {
+			handle = "@" + summary.Handle
+		}
+		token := summary.TokenStorage
+		if summary.TokenBackend != "" {
+			token += " (" + summary.TokenBackend + ")"
+		}
+		ui.Info(fmt.Sprintf("  %s %-16s %-40s token: %s", marker, summary.Name, handle, token))
+	}
+}
+
+func manageProfiles(paths *config.Paths) {
+	for {
+		ui.PrintHeader("Manage Profiles")
+		printProfiles(paths)
+		fmt.Println()
+		ui.Info("1) Switch profile")
+		ui.Info("2) Create profile")
+		ui.Info("3) Rename profile")
+		ui.Info("4) Delete profile")
+		ui.Info("5) Back")
+		fmt.Println()
+
+		var err error
+		switch ui.Prompt("Select an option (1-5): ") {
+		case "1":
+			err = switchProfile(paths, ui.Prompt("Profile to switch to: "))
+		case "2":
+			name := ui.Prompt("Name of the new profile: ")
+			err = createProfile(paths, name)
+			if err == nil && ui.Confirm("Switch to the new profile now? (yes/no): ") {
+				err = switchProfile(paths, name)
+			}
+		case "3":
+			from := ui.Prompt("Profile to rename [" + paths.Profile + "]: ")
+			if from == "" {
+				from = paths.Profile
+			}
+			err = renameProfile(paths, from, ui.Prompt("New name: "))
+		case "4":
+			name := ui.Prompt("Profile to delete: ")
+			ui.Warn("The Discord token and Fediverse handle of profile " + name + " will be PERMANENTLY deleted.")
+			if !confirmDeletion() {
+				err = ui.ErrCancelled
+				break
+			}
+			err = deleteProfile(paths, name)
+		case "5":
+			return
+		default:
+			err = errors.New("invalid option; please choose 1-5")
+		}
+
+		switch {
+		case errors.Is(err, ui.ErrCancelled):
+			ui.Info("Cancelled")
+		case err != nil:
+			ui.Error(err.Error())
+		}
+		fmt.Println()
+		ui.PressEnter()
+	}
+}
+
+func switchProfile(paths *config.Paths, name string) error {
+	if err := paths.SetDefaultProfile(name); err != nil {
+		return err
+	}
+	*paths = *paths.ForProfile(name)
+	history.Record(paths, "switched to profile "+name)
+	ui.Success("Switched to profile " + name)
+	return nil
+}
+
+func createProfile(paths *config.Paths, name string) error {
+	if err := paths.CreateProfile(name); err != nil {
+		return err
+	}
+	history.Record(paths, "profile "+name+" created")
+	ui.Success("Profile " + name + " created")
+	return nil
+}
+
+func renameProfile(paths *config.Paths, from, to string) error {
+	if err := paths.RenameProfile(from, to); err != nil {
+		return err
+	}
+	if err := storage.RenameToken(paths.ForProfile(from), paths.ForProfile(to)); err != nil {
+		return fmt.Errorf("profile %s was renamed, however its token could not be moved: %w", from, err)
+	}
+	if paths.Profile == from {
+		*paths = *paths.ForProfile(to)
+	}
+	history.Record(paths, "profile "+from+" renamed to "+to)
+	ui.Success("Profile " + from + " renamed to " + to)
+	return nil
+}
+
+func deleteProfile(paths *config.Paths, name string) error {
+	if err := paths.DeleteProfile(name); err != nil {
+		return err
+	}
+	if err := storage.DeleteToken(paths.ForProfile(name)); err != nil {
+		return fmt.Errorf("profile %s was deleted, however its token could not be removed: %w", name, err)
+	}
+	history.Record(paths, "profile "+name+" deleted")
+	ui.Success("Profile " + name + " deleted")
+	return nil
+}
+
+func runProfile(paths *config.Paths, args []string, res *result) error {
+	action := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+
+	fs := newFlagSet("profile " + action)
+	yes := fs.Bool("yes", false, "delete without asking for confirmation")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	operands := fs.Args()
+
+	var err error
+	switch {
+	case action == "list" && len(operands) == 0:
+		if !jsonOutput {
+			printProfiles(paths)
+		}
+	case action == "use" && len(operands) == 1:
+		err = switchProfile(paths, operands[0])
+	case action == "create" && len(operands) == 1:
+		err = createProfile(paths, operands[0])
+	case action == "rename" && len(operands) == 2:
+		err = renameProfile(paths, operands[0], operands[1])
+	case action == "delete" && len(operands) == 1:
+		if !*yes {
+			if !terminal.IsInputTerminal() {
+				return errors.New("refusing to delete a profile without confirmation; supply -yes")
+			}
+			if !confirmDeletion() {
+				return ui.ErrCancelled
+			}
+		}
+		err = deleteProfile(paths, operands[0])
+	default:
+		return errUsage
+	}
+	if err != nil {
+		return err
+	}
+
+	res.Profile = paths.Profile
+	res.Profiles, err = profileSummaries(paths)
+	return err
+}
@@ -2,4 +2,15 @@ module github.com/jimed-rand/fediscord
 
 go 1.21
 
-require golang.org/x/term v0.18.0
+require (
+	filippo.io/age v1.2.1
+	github.com/godbus/dbus/v5 v5.1.0
+	golang.org/x/net v0.22.0
+	golang.org/x/sys v0.21.0
+	golang.org/x/term v0.21.0
+)
+
+require (
+	golang.org/x/crypto v0.24.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
+)
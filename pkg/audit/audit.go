@@ -0,0 +1,115 @@
+package audit
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const credentialPrefix = "discord_token"
+
+var ErrInsecure = errors.New("the configuration directory or a credential file is accessible to other users")
+
+type Finding struct {
+	Path       string `json:"path"`
+	Problem    string `json:"problem"`
+	Critical   bool   `json:"critical"`
+	Repairable bool   `json:"repairable"`
+	dir        bool
+}
+
+func Check(dir string) ([]Finding, error) {
+	var findings []Finding
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir && os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if entry.Type()&fs.ModeSymlink != 0 && isCredential(path) {
+			found, err := inspectLink(path)
+			if err != nil {
+				return err
+			}
+			findings = append(findings, found...)
+			return nil
+		}
+		if !entry.IsDir() && !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		found, err := inspect(path, info, isCredential(path))
+		if err != nil {
+			return err
+		}
+		findings = append(findings, found...)
+		return nil
+	})
+	return findings, err
+}
+
+func HasCritical(findings []Finding) bool {
+	for _, finding := range findings {
+		if finding.Critical {
+			return true
+		}
+	}
+	return false
+}
+
+func Repair(findings []Finding) ([]Finding, error) {
+	var remaining []Finding
+	repaired := map[string]bool{}
+	for _, finding := range findings {
+		if !finding.Repairable {
+			remaining = append(remaining, finding)
+			continue
+		}
+		if repaired[finding.Path] {
+			continue
+		}
+		if err := repair(finding.Path, finding.dir); err != nil {
+			return remaining, err
+		}
+		repaired[finding.Path] = true
+	}
+	return remaining, nil
+}
+
+func inspectLink(path string) ([]Finding, error) {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return []Finding{{Path: path, Problem: "is a symbolic link to " + target + ", which does not exist"}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{{Path: path, Problem: "is a symbolic link to " + target}}
+	if !info.Mode().IsRegular() {
+		return append(findings, Finding{Path: path, Problem: "points at something other than a regular file", Critical: true}), nil
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, err
+	}
+	found, err := inspect(resolved, info, true)
+	if err != nil {
+		return nil, err
+	}
+	return append(findings, found...), nil
+}
+
+func isCredential(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), credentialPrefix)
+}
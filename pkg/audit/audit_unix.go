@@ -0,0 +1,50 @@
+//go:build !windows
+
+package audit
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+func inspect(path string, info fs.FileInfo, credential bool) ([]Finding, error) {
+	var findings []Finding
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Getuid() {
+		findings = append(findings, Finding{
+			Path:     path,
+			Problem:  fmt.Sprintf("is owned by user ID %d instead of %d", stat.Uid, os.Getuid()),
+			Critical: true,
+		})
+	}
+
+	mode, want := info.Mode().Perm(), fs.FileMode(0600)
+	if info.IsDir() {
+		want = 0700
+	}
+	if mode&0077 != 0 {
+		findings = append(findings, Finding{
+			Path:       path,
+			Problem:    fmt.Sprintf("has mode %04o instead of %04o", mode, want),
+			Critical:   credential || mode&0022 != 0,
+			Repairable: true,
+			dir:        info.IsDir(),
+		})
+	}
+	return findings, nil
+}
+
+func repair(path string, dir bool) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	want := info.Mode().Perm() &^ 0077
+	if dir {
+		want |= 0700
+	} else {
+		want |= 0600
+	}
+	return os.Chmod(path, want)
+}
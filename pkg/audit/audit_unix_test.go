@@ -0,0 +1,152 @@
+//go:build !windows
+
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(t *testing.T, dir string)
+		problems int
+		critical bool
+		lasting  bool
+	}{
+		{
+			name: "secure",
+			setup: func(t *testing.T, dir string) {
+				writeFile(t, filepath.Join(dir, "config.json"), 0600)
+				writeFile(t, filepath.Join(dir, "profiles", "default", "discord_token.age"), 0600)
+			},
+			problems: 0,
+		},
+		{
+			name: "readable token",
+			setup: func(t *testing.T, dir string) {
+				writeFile(t, filepath.Join(dir, "profiles", "default", "discord_token.txt"), 0644)
+			},
+			problems: 1,
+			critical: true,
+		},
+		{
+			name: "readable configuration",
+			setup: func(t *testing.T, dir string) {
+				writeFile(t, filepath.Join(dir, "config.json"), 0644)
+			},
+			problems: 1,
+		},
+		{
+			name: "writable directory",
+			setup: func(t *testing.T, dir string) {
+				if err := os.Chmod(dir, 0777); err != nil {
+					t.Fatal(err)
+				}
+			},
+			problems: 1,
+			critical: true,
+		},
+		{
+			name: "token linked to a readable file",
+			setup: func(t *testing.T, dir string) {
+				target := filepath.Join(t.TempDir(), "token")
+				writeFile(t, target, 0644)
+				link(t, target, filepath.Join(dir, "profiles", "default", "discord_token.txt"))
+			},
+			problems: 2,
+			critical: true,
+		},
+		{
+			name: "token linked to a private file",
+			setup: func(t *testing.T, dir string) {
+				target := filepath.Join(t.TempDir(), "token")
+				writeFile(t, target, 0600)
+				link(t, target, filepath.Join(dir, "profiles", "default", "discord_token.txt"))
+			},
+			problems: 1,
+		},
+		{
+			name: "token linked to a directory",
+			setup: func(t *testing.T, dir string) {
+				link(t, t.TempDir(), filepath.Join(dir, "profiles", "default", "discord_token.txt"))
+			},
+			problems: 2,
+			critical: true,
+			lasting:  true,
+		},
+		{
+			name: "dangling token link",
+			setup: func(t *testing.T, dir string) {
+				link(t, filepath.Join(t.TempDir(), "missing"), filepath.Join(dir, "profiles", "default", "discord_token.txt"))
+			},
+			problems: 1,
+		},
+		{
+			name: "linked configuration is ignored",
+			setup: func(t *testing.T, dir string) {
+				target := filepath.Join(t.TempDir(), "config.json")
+				writeFile(t, target, 0644)
+				link(t, target, filepath.Join(dir, "config.json"))
+			},
+			problems: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "fediscord")
+			if err := os.MkdirAll(filepath.Join(dir, "profiles", "default"), 0700); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(filepath.Join(dir, "profiles"), 0700); err != nil {
+				t.Fatal(err)
+			}
+			tt.setup(t, dir)
+
+			findings, err := Check(dir)
+			if err != nil {
+				t.Fatalf("Check failed: %v", err)
+			}
+			if len(findings) != tt.problems {
+				t.Errorf("Check found %d problems, want %d: %+v", len(findings), tt.problems, findings)
+			}
+			if HasCritical(findings) != tt.critical {
+				t.Errorf("HasCritical = %v, want %v: %+v", !tt.critical, tt.critical, findings)
+			}
+
+			if _, err := Repair(findings); err != nil {
+				t.Fatalf("Repair failed: %v", err)
+			}
+			findings, err = Check(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if HasCritical(findings) && !tt.lasting {
+				t.Errorf("critical problems remain after the repair: %+v", findings)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, path string, perm os.FileMode) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("data"), perm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, perm); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func link(t *testing.T, target, path string) {
+	t.Helper()
+	if err := os.Symlink(target, path); err != nil {
+		t.Skipf("symbolic links are not supported: %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+//go:build windows
+
+package audit
+
+import (
+	"fmt"
+	"io/fs"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	accessAllowedACEType = 0
+	readAccess           = windows.FILE_READ_DATA | windows.GENERIC_READ | windows.GENERIC_ALL
+	writeAccess          = windows.FILE_WRITE_DATA | windows.FILE_APPEND_DATA | windows.GENERIC_WRITE |
+		windows.GENERIC_ALL | windows.WRITE_DAC | windows.WRITE_OWNER
+)
+
+type aclHeader struct {
+	revision byte
+	sbz1     byte
+	size     uint16
+	count    uint16
+	sbz2     uint16
+}
+
+type accessAllowedACE struct {
+	aceType  byte
+	flags    byte
+	size     uint16
+	mask     uint32
+	sidStart uint32
+}
+
+func inspect(path string, info fs.FileInfo, credential bool) ([]Finding, error) {
+	sd, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT,
+		windows.OWNER_SECURITY_INFORMATION|windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return nil, fmt.Errorf("the security descriptor of %s could not be read: %w", path, err)
+	}
+	user, err := currentUser()
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	owner, _, err := sd.Owner()
+	if err == nil && !owner.Equals(user) && !owner.IsWellKnown(windows.WinBuiltinAdministratorsSid) {
+		findings = append(findings, Finding{
+			Path:     path,
+			Problem:  "is owned by " + accountName(owner) + " instead of the current user",
+			Critical: true,
+		})
+	}
+
+	dacl, _, err := sd.DACL()
+	if err != nil || dacl == nil {
+		return append(findings, Finding{
+			Path:       path,
+			Problem:    "has no access control list, so everyone has full access",
+			Critical:   true,
+			Repairable: true,
+			dir:        info.IsDir(),
+		}), nil
+	}
+
+	header := (*aclHeader)(unsafe.Pointer(dacl))
+	offset := unsafe.Sizeof(aclHeader{})
+	for i := 0; i < int(header.count); i++ {
+		ace := (*accessAllowedACE)(unsafe.Add(unsafe.Pointer(dacl), offset))
+		offset += uintptr(ace.size)
+		if ace.aceType != accessAllowedACEType || ace.flags&windows.INHERIT_ONLY_ACE != 0 {
+			continue
+		}
+		sid := (*windows.SID)(unsafe.Pointer(&ace.sidStart))
+		if sid.Equals(user) || sid.IsWellKnown(windows.WinLocalSystemSid) || sid.IsWellKnown(windows.WinBuiltinAdministratorsSid) {
+			continue
+		}
+		writable := ace.mask&writeAccess != 0
+		if !writable && ace.mask&readAccess == 0 {
+			continue
+		}
+		problem := "can be read by " + accountName(sid)
+		if writable {
+			problem = "can be modified by " + accountName(sid)
+		}
+		findings = append(findings, Finding{
+			Path:       path,
+			Problem:    problem,
+			Critical:   credential || writable,
+			Repairable: true,
+			dir:        info.IsDir(),
+		})
+	}
+	return findings, nil
+}
+
+func repair(path string, dir bool) error {
+	user, err := currentUser()
+	if err != nil {
+		return err
+	}
+	system, err := windows.CreateWellKnownSid(windows.WinLocalSystemSid)
+	if err != nil {
+		return err
+	}
+	admins, err := windows.CreateWellKnownSid(windows.WinBuiltinAdministratorsSid)
+	if err != nil {
+		return err
+	}
+
+	inheritance := uint32(windows.NO_INHERITANCE)
+	if dir {
+		inheritance = windows.SUB_CONTAINERS_AND_OBJECTS_INHERIT
+	}
+	var entries []windows.EXPLICIT_ACCESS
+	for _, trustee := range []struct {
+		sid  *windows.SID
+		kind windows.TRUSTEE_TYPE
+	}{
+		{user, windows.TRUSTEE_IS_USER},
+		{system, windows.TRUSTEE_IS_WELL_KNOWN_GROUP},
+		{admins, windows.TRUSTEE_IS_WELL_KNOWN_GROUP},
+	} {
+		entries = append(entries, windows.EXPLICIT_ACCESS{
+			AccessPermissions: windows.GENERIC_ALL,
+			AccessMode:        windows.SET_ACCESS,
+			Inheritance:       inheritance,
+			Trustee: windows.TRUSTEE{
+				TrusteeForm:  windows.TRUSTEE_IS_SID,
+				TrusteeType:  trustee.kind,
+				TrusteeValue: windows.TrusteeValueFromSID(trustee.sid),
+			},
+		})
+	}
+	acl, err := windows.ACLFromEntries(entries, nil)
+	if err != nil {
+		return err
+	}
+	return windows.SetNamedSecurityInfo(path, windows.SE_FILE_OBJECT,
+		windows.DACL_SECURITY_INFORMATION|windows.PROTECTED_DACL_SECURITY_INFORMATION, nil, nil, acl, nil)
+}
+
+func currentUser() (*windows.SID, error) {
+	user, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		return nil, fmt.Errorf("the current user could not be determined: %w", err)
+	}
+	return user.User.Sid, nil
+}
+
+func accountName(sid *windows.SID) string {
+	account, domain, _, err := sid.LookupAccount("")
+	if err != nil {
+		return sid.String()
+	}
+	if domain != "" {
+		return domain + `\` + account
+	}
+	return account
+}
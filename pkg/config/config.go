@@ -6,15 +6,39 @@ import (
 	"runtime"
 )
 
+const (
+	appName      = "fediverse-discord"
+	ConfigDirEnv = "FEDISCORD_CONFIG_DIR"
+)
+
+const (
+	tokenAgeFile   = "discord_token.age"
+	tokenGPGFile   = "discord_token.enc"
+	tokenPlainFile = "discord_token.txt"
+)
+
 type Paths struct {
-	Dir            string
-	TokenEncrypted string
-	TokenPlain     string
-	HandleFile     string
-	EncryptionFlag string
+	Dir           string
+	StateDir      string
+	CacheDir      string
+	ConfigFile    string
+	HistoryFile   string
+	InstanceCache string
+	Profile       string
+	ProfileDir    string
+	TokenAge      string
+	TokenGPG      string
+	TokenPlain    string
+	LegacyDir     string
+	MigratedFrom  string
+	Unmigrated    []string
 }
 
 func resolveConfigDirectory() (string, error) {
+	if dir := os.Getenv(ConfigDirEnv); dir != "" {
+		return filepath.Abs(dir)
+	}
+
 	switch runtime.GOOS {
 	case "windows":
 		base := os.Getenv("APPDATA")
@@ -25,37 +49,142 @@ func resolveConfigDirectory() (string, error) {
 			}
 			base = home
 		}
-		return filepath.Join(base, "fediverse-discord"), nil
+		return filepath.Join(base, appName), nil
 	case "darwin":
 		home, err := os.UserHomeDir()
 		if err != nil {
 			return "", err
 		}
-		return filepath.Join(home, "Library", "Application Support", "fediverse-discord"), nil
+		return filepath.Join(home, "Library", "Application Support", appName), nil
 	default:
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return "", err
-		}
-		return filepath.Join(home, ".fediverse-discord"), nil
+		return xdgDirectory("XDG_CONFIG_HOME", ".config")
 	}
 }
 
+func resolveStateDirectory(configDir string) (string, error) {
+	if os.Getenv(ConfigDirEnv) != "" || runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		return filepath.Join(configDir, "state"), nil
+	}
+	return xdgDirectory("XDG_STATE_HOME", filepath.Join(".local", "state"))
+}
+
+func resolveCacheDirectory(configDir string) (string, error) {
+	if os.Getenv(ConfigDirEnv) != "" {
+		return filepath.Join(configDir, "cache"), nil
+	}
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(configDir, "cache"), nil
+	}
+	return filepath.Join(base, appName), nil
+}
+
+func xdgDirectory(variable, fallback string) (string, error) {
+	if base := os.Getenv(variable); filepath.IsAbs(base) {
+		return filepath.Join(base, appName), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, fallback, appName), nil
+}
+
+func legacyDirectory() string {
+	if os.Getenv(ConfigDirEnv) != "" || runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		return ""
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".fediverse-discord")
+}
+
 func Load() (*Paths, error) {
 	dir, err := resolveConfigDirectory()
 	if err != nil {
 		return nil, err
 	}
+	stateDir, err := resolveStateDirectory(dir)
+	if err != nil {
+		return nil, err
+	}
+	cacheDir, err := resolveCacheDirectory(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := &Paths{
+		Dir:           dir,
+		StateDir:      stateDir,
+		CacheDir:      cacheDir,
+		ConfigFile:    filepath.Join(dir, "config.json"),
+		HistoryFile:   filepath.Join(stateDir, "history.log"),
+		InstanceCache: filepath.Join(cacheDir, "instances.json"),
+		LegacyDir:     legacyDirectory(),
+	}
+	if err := paths.migrateLegacyDirectory(paths.LegacyDir); err != nil {
+		return nil, err
+	}
+	if err := paths.migrateLegacyFiles(); err != nil {
+		return nil, err
+	}
+
+	cfg, err := paths.ReadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if err := paths.migrateLegacyTokens(cfg.DefaultProfile); err != nil {
+		return nil, err
+	}
+	profile := paths.ForProfile(cfg.DefaultProfile)
+	profile.MigratedFrom = paths.MigratedFrom
+	profile.Unmigrated = paths.Unmigrated
+	return profile, nil
+}
 
+func (p *Paths) ForProfile(name string) *Paths {
+	profileDir := filepath.Join(p.Dir, "profiles", name)
 	return &Paths{
-		Dir:            dir,
-		TokenEncrypted: filepath.Join(dir, "discord_token.enc"),
-		TokenPlain:     filepath.Join(dir, "discord_token.txt"),
-		HandleFile:     filepath.Join(dir, "fediverse_handle.txt"),
-		EncryptionFlag: filepath.Join(dir, ".use_encryption"),
-	}, nil
+		Dir:           p.Dir,
+		StateDir:      p.StateDir,
+		CacheDir:      p.CacheDir,
+		ConfigFile:    p.ConfigFile,
+		HistoryFile:   p.HistoryFile,
+		InstanceCache: p.InstanceCache,
+		LegacyDir:     p.LegacyDir,
+		Profile:       name,
+		ProfileDir:    profileDir,
+		TokenAge:      filepath.Join(profileDir, tokenAgeFile),
+		TokenGPG:      filepath.Join(profileDir, tokenGPGFile),
+		TokenPlain:    filepath.Join(profileDir, tokenPlainFile),
+	}
+}
+
+func (p *Paths) CustomDir() bool {
+	return os.Getenv(ConfigDirEnv) != ""
+}
+
+func (p *Paths) DataFiles() []string {
+	files := []string{p.ConfigFile, p.HistoryFile, p.InstanceCache}
+	for _, dir := range []string{p.Dir, p.LegacyDir} {
+		if dir == "" {
+			continue
+		}
+		for _, name := range []string{legacyHandleFile, legacyEncryptionFlag, tokenGPGFile, tokenPlainFile} {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	if p.LegacyDir != "" {
+		files = append(files, filepath.Join(p.LegacyDir, legacyNoticeFile))
+	}
+	return files
 }
 
 func (p *Paths) Initialise() error {
-	return os.MkdirAll(p.Dir, 0700)
+	if p.ProfileDir == "" {
+		return os.MkdirAll(p.Dir, 0700)
+	}
+	return os.MkdirAll(p.ProfileDir, 0700)
 }
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+const (
+	CurrentVersion = 1
+	DefaultProfile = "default"
+)
+
+const (
+	EncryptionAge           = "age"
+	EncryptionGPG           = "gpg"
+	EncryptionSecretService = "secret-service"
+	EncryptionPass          = "pass"
+	EncryptionPlain         = "plain"
+)
+
+var ErrUnsupportedVersion = errors.New("the configuration file was written by a newer version of fediscord")
+
+type Config struct {
+	Version        int                 `json:"version"`
+	DefaultProfile string              `json:"default_profile"`
+	Profiles       map[string]*Profile `json:"profiles"`
+	API            API                 `json:"api"`
+	Preferences    Preferences         `json:"preferences"`
+}
+
+type Profile struct {
+	Handle     string `json:"handle,omitempty"`
+	Encryption string `json:"encryption,omitempty"`
+}
+
+type API struct {
+	Discord string `json:"discord,omitempty"`
+}
+
+type Preferences struct {
+	SkipTokenVerification bool `json:"skip_token_verification"`
+	FollowMovedAccounts   bool `json:"follow_moved_accounts"`
+	RecordHistory         bool `json:"record_history"`
+}
+
+func Default() *Config {
+	return &Config{
+		Version:        CurrentVersion,
+		DefaultProfile: DefaultProfile,
+		Profiles:       map[string]*Profile{DefaultProfile: {}},
+	}
+}
+
+func (c *Config) Profile(name string) *Profile {
+	if c.Profiles == nil {
+		c.Profiles = make(map[string]*Profile)
+	}
+	profile, ok := c.Profiles[name]
+	if !ok || profile == nil {
+		profile = &Profile{}
+		c.Profiles[name] = profile
+	}
+	return profile
+}
+
+func (c *Config) normalise() {
+	c.Version = CurrentVersion
+	if c.DefaultProfile == "" {
+		c.DefaultProfile = DefaultProfile
+	}
+	c.Profile(c.DefaultProfile)
+}
+
+func (p *Paths) ReadConfig() (*Config, error) {
+	data, err := os.ReadFile(p.ConfigFile)
+	if os.IsNotExist(err) {
+		return Default(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("the configuration file %s could not be parsed: %w", p.ConfigFile, err)
+	}
+	if cfg.Version > CurrentVersion {
+		return nil, fmt.Errorf("%w: %s has version %d, while version %d is supported", ErrUnsupportedVersion, p.ConfigFile, cfg.Version, CurrentVersion)
+	}
+	cfg.normalise()
+	for name := range cfg.Profiles {
+		if !profileNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("%w: %s names the invalid profile %q", ErrProfileNotFound, p.ConfigFile, name)
+		}
+	}
+	return cfg, nil
+}
+
+func (p *Paths) WriteConfig(cfg *Config) error {
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(p.ConfigFile, append(data, '\n'), 0600)
+}
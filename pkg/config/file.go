@@ -0,0 +1,137 @@
+package config
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, temporaryPrefix(path)+"*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDirectory(dir)
+}
+
+func TemporaryFiles(path string) []string {
+	dir, prefix := filepath.Dir(path), temporaryPrefix(path)
+	entries, _ := os.ReadDir(dir)
+	var files []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), prefix) {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files
+}
+
+func temporaryPrefix(path string) string {
+	return "." + filepath.Base(path) + ".tmp-"
+}
+
+func WipeFile(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode().IsRegular() && info.Size() > 0 {
+		if err := overwriteFile(path, info.Size()); err != nil {
+			return fmt.Errorf("%s could not be overwritten: %w", path, err)
+		}
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func WipeDirectory(dir string) ([]string, error) {
+	var wiped []string
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			if path == dir && os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		if err := WipeFile(path); err != nil {
+			return err
+		}
+		wiped = append(wiped, path)
+		return nil
+	})
+	if err != nil {
+		return wiped, err
+	}
+	return wiped, os.RemoveAll(dir)
+}
+
+func overwriteFile(path string, size int64) error {
+	if err := os.Chmod(path, 0600); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 32*1024)
+	for remaining := size; remaining > 0; {
+		chunk := buf
+		if remaining < int64(len(chunk)) {
+			chunk = chunk[:remaining]
+		}
+		if _, err := rand.Read(chunk); err != nil {
+			file.Close()
+			return err
+		}
+		if _, err := file.Write(chunk); err != nil {
+			file.Close()
+			return err
+		}
+		remaining -= int64(len(chunk))
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Truncate(0); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
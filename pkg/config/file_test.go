@@ -0,0 +1,134 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWipeFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		perm     os.FileMode
+		symlink  bool
+		missing  bool
+	}{
+		{name: "token file", contents: "secret-token-value", perm: 0600},
+		{name: "larger than the buffer", contents: string(make([]byte, 100*1024)), perm: 0600},
+		{name: "read-only file", contents: "secret-token-value", perm: 0400},
+		{name: "empty file", perm: 0600},
+		{name: "symbolic link", contents: "secret-token-value", perm: 0600, symlink: true},
+		{name: "missing file", missing: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "discord_token.txt")
+			observer := filepath.Join(dir, "observer")
+
+			if !tt.missing {
+				target := path
+				if tt.symlink {
+					target = filepath.Join(dir, "target")
+				}
+				if err := os.WriteFile(target, []byte(tt.contents), tt.perm); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Link(target, observer); err != nil {
+					t.Skipf("hard links are not supported: %v", err)
+				}
+				if tt.symlink {
+					if err := os.Symlink(target, path); err != nil {
+						t.Skipf("symbolic links are not supported: %v", err)
+					}
+				}
+			}
+
+			if err := WipeFile(path); err != nil {
+				t.Fatalf("WipeFile failed: %v", err)
+			}
+			if exists(path) {
+				t.Errorf("%s still exists", path)
+			}
+			if tt.missing {
+				return
+			}
+
+			data, err := os.ReadFile(observer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch {
+			case tt.symlink && string(data) != tt.contents:
+				t.Errorf("the target of the symbolic link was modified: %q", data)
+			case !tt.symlink && len(data) != 0:
+				t.Errorf("the contents of the file survived: %d bytes remain", len(data))
+			}
+		})
+	}
+}
+
+func TestWipeDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "profile")
+	writeTestFiles(t, dir, map[string]string{
+		tokenPlainFile:           "secret-token-value",
+		"nested/" + tokenAgeFile: "age-encrypted-token",
+	})
+	observer := filepath.Join(filepath.Dir(dir), "observer")
+	if err := os.Link(filepath.Join(dir, tokenPlainFile), observer); err != nil {
+		t.Skipf("hard links are not supported: %v", err)
+	}
+
+	wiped, err := WipeDirectory(dir)
+	if err != nil {
+		t.Fatalf("WipeDirectory failed: %v", err)
+	}
+	if len(wiped) != 2 {
+		t.Errorf("WipeDirectory wiped %d files, want 2", len(wiped))
+	}
+	if exists(dir) {
+		t.Errorf("%s still exists", dir)
+	}
+	if data := readTestFile(t, observer); data != "" {
+		t.Errorf("the contents of the token file survived: %q", data)
+	}
+
+	if wiped, err := WipeDirectory(dir); err != nil || len(wiped) != 0 {
+		t.Errorf("WipeDirectory on a missing directory = %v, %v", wiped, err)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		subdir   string
+	}{
+		{name: "new file"},
+		{name: "replaces existing file", existing: "old contents"},
+		{name: "creates missing directory", subdir: "profiles/default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), filepath.FromSlash(tt.subdir), "config.json")
+			if tt.existing != "" {
+				if err := os.WriteFile(path, []byte(tt.existing), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := WriteFileAtomic(path, []byte("new contents"), 0600); err != nil {
+				t.Fatalf("WriteFileAtomic failed: %v", err)
+			}
+			if got := readTestFile(t, path); got != "new contents" {
+				t.Errorf("contents = %q, want %q", got, "new contents")
+			}
+			if temporary := TemporaryFiles(path); len(temporary) != 0 {
+				t.Errorf("temporary files were left behind: %v", temporary)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	lockFileName = ".lock"
+	lockInterval = 100 * time.Millisecond
+)
+
+var lockTimeout = 10 * time.Second
+
+var ErrLocked = errors.New("another fediscord process is using the configuration directory")
+
+type heldLock struct {
+	file  *os.File
+	count int
+}
+
+var (
+	locksMu sync.Mutex
+	locks   = map[string]*heldLock{}
+)
+
+func (p *Paths) Lock() (func(), error) {
+	locksMu.Lock()
+	defer locksMu.Unlock()
+
+	if held, ok := locks[p.Dir]; ok {
+		held.count++
+		return p.unlocker(), nil
+	}
+
+	if err := os.MkdirAll(p.Dir, 0700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(p.LockFile(), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		acquired, err := tryLockFile(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("the configuration directory could not be locked: %w", err)
+		}
+		if acquired {
+			break
+		}
+		if time.Now().After(deadline) {
+			file.Close()
+			return nil, fmt.Errorf("%w: %s", ErrLocked, p.Dir)
+		}
+		time.Sleep(lockInterval)
+	}
+
+	locks[p.Dir] = &heldLock{file: file, count: 1}
+	return p.unlocker(), nil
+}
+
+func (p *Paths) LockFile() string {
+	return filepath.Join(p.Dir, lockFileName)
+}
+
+func (p *Paths) unlocker() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			locksMu.Lock()
+			defer locksMu.Unlock()
+
+			held, ok := locks[p.Dir]
+			if !ok {
+				return
+			}
+			held.count--
+			if held.count > 0 {
+				return
+			}
+			unlockFile(held.file)
+			held.file.Close()
+			delete(locks, p.Dir)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func lockedByOther(t *testing.T, paths *Paths) bool {
+	t.Helper()
+	file, err := os.OpenFile(paths.LockFile(), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	acquired, err := tryLockFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acquired {
+		unlockFile(file)
+	}
+	return !acquired
+}
+
+func TestLockReentrant(t *testing.T) {
+	paths := newTestPaths(t)
+
+	unlockOuter, err := paths.Lock()
+	if err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+	unlockInner, err := paths.ForProfile("work").Lock()
+	if err != nil {
+		t.Fatalf("nested Lock failed: %v", err)
+	}
+	if !lockedByOther(t, paths) {
+		t.Fatal("the configuration directory is not locked")
+	}
+
+	unlockInner()
+	unlockInner()
+	if !lockedByOther(t, paths) {
+		t.Fatal("releasing the nested lock released the outer lock")
+	}
+
+	unlockOuter()
+	if lockedByOther(t, paths) {
+		t.Fatal("the lock was not released")
+	}
+
+	unlock, err := paths.Lock()
+	if err != nil {
+		t.Fatalf("Lock after release failed: %v", err)
+	}
+	unlock()
+}
+
+func TestLockTimeout(t *testing.T) {
+	previous := lockTimeout
+	lockTimeout = 300 * time.Millisecond
+	t.Cleanup(func() { lockTimeout = previous })
+
+	tests := []struct {
+		name    string
+		release time.Duration
+		wantErr error
+	}{
+		{name: "released before the timeout", release: 100 * time.Millisecond},
+		{name: "held past the timeout", wantErr: ErrLocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths := newTestPaths(t)
+			if err := os.MkdirAll(paths.Dir, 0700); err != nil {
+				t.Fatal(err)
+			}
+			other, err := os.OpenFile(paths.LockFile(), os.O_RDWR|os.O_CREATE, 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer other.Close()
+			if acquired, err := tryLockFile(other); err != nil || !acquired {
+				t.Fatalf("the lock could not be taken by the other holder: %v", err)
+			}
+
+			released := make(chan struct{})
+			if tt.release > 0 {
+				time.AfterFunc(tt.release, func() {
+					unlockFile(other)
+					close(released)
+				})
+			} else {
+				defer unlockFile(other)
+			}
+
+			start := time.Now()
+			unlock, err := paths.Lock()
+			elapsed := time.Since(start)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Lock = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if elapsed < lockTimeout || elapsed > lockTimeout+time.Second {
+					t.Errorf("Lock gave up after %v, want about %v", elapsed, lockTimeout)
+				}
+				return
+			}
+			defer unlock()
+			<-released
+			if elapsed < tt.release {
+				t.Errorf("Lock succeeded after %v, before the other holder released it", elapsed)
+			}
+		})
+	}
+}
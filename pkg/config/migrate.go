@@ -0,0 +1,165 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	legacyHandleFile     = "fediverse_handle.txt"
+	legacyEncryptionFlag = ".use_encryption"
+	legacyNoticeFile     = "MOVED.txt"
+)
+
+func (p *Paths) migrateLegacyDirectory(legacy string) error {
+	if legacy == "" || legacy == p.Dir {
+		return nil
+	}
+	if _, err := os.Stat(p.Dir); !os.IsNotExist(err) {
+		return nil
+	}
+	entries, err := os.ReadDir(legacy)
+	if err != nil || len(entries) == 0 || isNoticeOnly(entries) {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p.Dir), 0700); err != nil {
+		return fmt.Errorf("the configuration directory could not be created: %w", err)
+	}
+	if err := os.Rename(legacy, p.Dir); err != nil {
+		if err := copyDirectory(legacy, p.Dir); err != nil {
+			os.RemoveAll(p.Dir)
+			return fmt.Errorf("the legacy configuration directory %s could not be migrated: %w", legacy, err)
+		}
+		if _, err := WipeDirectory(legacy); err != nil {
+			return fmt.Errorf("the legacy configuration directory %s could not be removed after migration: %w", legacy, err)
+		}
+	}
+
+	notice := fmt.Sprintf("The fediscord configuration formerly stored in this directory has been moved to:\n\n  %s\n\nThis directory is no longer used and may be deleted.\n", p.Dir)
+	if err := os.MkdirAll(legacy, 0700); err == nil {
+		WriteFileAtomic(filepath.Join(legacy, legacyNoticeFile), []byte(notice), 0600)
+	}
+	p.MigratedFrom = legacy
+	return nil
+}
+
+func isNoticeOnly(entries []os.DirEntry) bool {
+	return len(entries) == 1 && entries[0].Name() == legacyNoticeFile
+}
+
+func copyDirectory(source, target string) error {
+	return filepath.WalkDir(source, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(target, rel)
+
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(dest, 0700)
+		case entry.Type().IsRegular():
+			return copyFile(path, dest)
+		default:
+			return errors.New("unsupported file type: " + path)
+		}
+	})
+}
+
+func copyFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func (p *Paths) migrateLegacyFiles() error {
+	if _, err := os.Stat(p.ConfigFile); !os.IsNotExist(err) {
+		return nil
+	}
+
+	handleFile := filepath.Join(p.Dir, legacyHandleFile)
+	flagFile := filepath.Join(p.Dir, legacyEncryptionFlag)
+	handle, handleErr := os.ReadFile(handleFile)
+	flag, flagErr := os.ReadFile(flagFile)
+	if os.IsNotExist(handleErr) && os.IsNotExist(flagErr) {
+		return nil
+	}
+
+	unlock, err := p.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if _, err := os.Stat(p.ConfigFile); !os.IsNotExist(err) {
+		return nil
+	}
+
+	cfg := Default()
+	profile := cfg.Profile(cfg.DefaultProfile)
+	if handleErr == nil {
+		profile.Handle = strings.TrimSpace(string(handle))
+	}
+	if flagErr == nil {
+		profile.Encryption = EncryptionPlain
+		if strings.TrimSpace(string(flag)) == "true" {
+			profile.Encryption = EncryptionGPG
+		}
+	}
+
+	if err := p.WriteConfig(cfg); err != nil {
+		return fmt.Errorf("the legacy configuration could not be migrated: %w", err)
+	}
+	os.Remove(handleFile)
+	os.Remove(flagFile)
+	return nil
+}
+
+func (p *Paths) migrateLegacyTokens(profile string) error {
+	target := p.ForProfile(profile)
+	for _, name := range []string{tokenGPGFile, tokenPlainFile} {
+		legacy := filepath.Join(p.Dir, name)
+		if _, err := os.Stat(legacy); err != nil {
+			continue
+		}
+		if target.hasToken() {
+			p.Unmigrated = append(p.Unmigrated, legacy)
+			continue
+		}
+		if err := target.Initialise(); err != nil {
+			return err
+		}
+		if err := os.Rename(legacy, filepath.Join(target.ProfileDir, name)); err != nil {
+			return fmt.Errorf("the stored token could not be moved into profile %q: %w", profile, err)
+		}
+	}
+	return nil
+}
+
+func (p *Paths) hasToken() bool {
+	for _, file := range []string{p.TokenAge, p.TokenGPG, p.TokenPlain} {
+		if _, err := os.Lstat(file); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,293 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestPaths(t *testing.T) *Paths {
+	t.Helper()
+	root := t.TempDir()
+	dir := filepath.Join(root, "config")
+	return &Paths{
+		Dir:           dir,
+		StateDir:      filepath.Join(root, "state"),
+		CacheDir:      filepath.Join(root, "cache"),
+		ConfigFile:    filepath.Join(dir, "config.json"),
+		HistoryFile:   filepath.Join(root, "state", "history.log"),
+		InstanceCache: filepath.Join(root, "cache", "instances.json"),
+	}
+}
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%s could not be read: %v", path, err)
+	}
+	return string(data)
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+func TestMigrateLegacyFiles(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      map[string]string
+		handle     string
+		encryption string
+		migrated   bool
+	}{
+		{
+			name:       "handle and encryption flag",
+			files:      map[string]string{legacyHandleFile: "alice@example.social\n", legacyEncryptionFlag: "true\n"},
+			handle:     "alice@example.social",
+			encryption: EncryptionGPG,
+			migrated:   true,
+		},
+		{
+			name:       "plain text flag",
+			files:      map[string]string{legacyHandleFile: "alice@example.social", legacyEncryptionFlag: "false"},
+			handle:     "alice@example.social",
+			encryption: EncryptionPlain,
+			migrated:   true,
+		},
+		{
+			name:     "handle only",
+			files:    map[string]string{legacyHandleFile: " alice@example.social "},
+			handle:   "alice@example.social",
+			migrated: true,
+		},
+		{
+			name:       "flag only",
+			files:      map[string]string{legacyEncryptionFlag: "true"},
+			encryption: EncryptionGPG,
+			migrated:   true,
+		},
+		{
+			name: "no legacy files",
+		},
+		{
+			name: "configuration already present",
+			files: map[string]string{
+				"config.json":        `{"version": 1, "default_profile": "default", "profiles": {"default": {"handle": "bob@example.social"}}}`,
+				legacyHandleFile:     "alice@example.social",
+				legacyEncryptionFlag: "true",
+			},
+			handle: "bob@example.social",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths := newTestPaths(t)
+			if err := os.MkdirAll(paths.Dir, 0700); err != nil {
+				t.Fatal(err)
+			}
+			writeTestFiles(t, paths.Dir, tt.files)
+
+			if err := paths.migrateLegacyFiles(); err != nil {
+				t.Fatalf("migrateLegacyFiles failed: %v", err)
+			}
+
+			cfg, err := paths.ReadConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			profile := cfg.Profile(DefaultProfile)
+			if profile.Handle != tt.handle {
+				t.Errorf("Handle = %q, want %q", profile.Handle, tt.handle)
+			}
+			if profile.Encryption != tt.encryption {
+				t.Errorf("Encryption = %q, want %q", profile.Encryption, tt.encryption)
+			}
+			for _, name := range []string{legacyHandleFile, legacyEncryptionFlag} {
+				_, present := tt.files[name]
+				if want := present && !tt.migrated; exists(filepath.Join(paths.Dir, name)) != want {
+					t.Errorf("%s present = %v, want %v", name, !want, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateLegacyTokens(t *testing.T) {
+	tests := []struct {
+		name       string
+		legacy     map[string]string
+		profile    map[string]string
+		want       map[string]string
+		unmigrated []string
+	}{
+		{
+			name:   "plain text token",
+			legacy: map[string]string{tokenPlainFile: "legacy"},
+			want:   map[string]string{tokenPlainFile: "legacy"},
+		},
+		{
+			name:   "gpg token",
+			legacy: map[string]string{tokenGPGFile: "legacy"},
+			want:   map[string]string{tokenGPGFile: "legacy"},
+		},
+		{
+			name: "no legacy token",
+			want: map[string]string{},
+		},
+		{
+			name:       "same token file already in profile",
+			legacy:     map[string]string{tokenPlainFile: "legacy"},
+			profile:    map[string]string{tokenPlainFile: "current"},
+			want:       map[string]string{tokenPlainFile: "current"},
+			unmigrated: []string{tokenPlainFile},
+		},
+		{
+			name:       "other token file already in profile",
+			legacy:     map[string]string{tokenGPGFile: "legacy"},
+			profile:    map[string]string{tokenAgeFile: "current"},
+			want:       map[string]string{tokenAgeFile: "current"},
+			unmigrated: []string{tokenGPGFile},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths := newTestPaths(t)
+			profile := paths.ForProfile(DefaultProfile)
+			if err := os.MkdirAll(paths.Dir, 0700); err != nil {
+				t.Fatal(err)
+			}
+			writeTestFiles(t, paths.Dir, tt.legacy)
+			writeTestFiles(t, profile.ProfileDir, tt.profile)
+
+			if err := paths.migrateLegacyTokens(DefaultProfile); err != nil {
+				t.Fatalf("migrateLegacyTokens failed: %v", err)
+			}
+
+			for _, name := range []string{tokenAgeFile, tokenGPGFile, tokenPlainFile} {
+				path := filepath.Join(profile.ProfileDir, name)
+				want, ok := tt.want[name]
+				if !ok {
+					if exists(path) {
+						t.Errorf("%s was created", name)
+					}
+					continue
+				}
+				if got := readTestFile(t, path); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+
+			var unmigrated []string
+			for _, path := range paths.Unmigrated {
+				unmigrated = append(unmigrated, filepath.Base(path))
+				if !exists(path) {
+					t.Errorf("the unmigrated token %s was removed", path)
+				}
+			}
+			if !reflect.DeepEqual(unmigrated, tt.unmigrated) {
+				t.Errorf("Unmigrated = %v, want %v", unmigrated, tt.unmigrated)
+			}
+			for name := range tt.legacy {
+				if exists(filepath.Join(paths.Dir, name)) != (len(tt.unmigrated) > 0) {
+					t.Errorf("the legacy token %s was not handled", name)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateLegacyDirectory(t *testing.T) {
+	tests := []struct {
+		name     string
+		legacy   map[string]string
+		current  map[string]string
+		migrated bool
+	}{
+		{
+			name:     "legacy directory with files",
+			legacy:   map[string]string{legacyHandleFile: "alice@example.social", tokenPlainFile: "token"},
+			migrated: true,
+		},
+		{
+			name:    "configuration directory already present",
+			legacy:  map[string]string{legacyHandleFile: "alice@example.social"},
+			current: map[string]string{"config.json": "{}"},
+		},
+		{
+			name:   "only the notice remains",
+			legacy: map[string]string{legacyNoticeFile: "moved"},
+		},
+		{
+			name:   "empty legacy directory",
+			legacy: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths := newTestPaths(t)
+			legacy := filepath.Join(filepath.Dir(paths.Dir), "legacy")
+			if err := os.MkdirAll(legacy, 0700); err != nil {
+				t.Fatal(err)
+			}
+			writeTestFiles(t, legacy, tt.legacy)
+			if tt.current != nil {
+				if err := os.MkdirAll(paths.Dir, 0700); err != nil {
+					t.Fatal(err)
+				}
+				writeTestFiles(t, paths.Dir, tt.current)
+			}
+
+			if err := paths.migrateLegacyDirectory(legacy); err != nil {
+				t.Fatalf("migrateLegacyDirectory failed: %v", err)
+			}
+
+			if migrated := paths.MigratedFrom == legacy; migrated != tt.migrated {
+				t.Fatalf("MigratedFrom = %q, want migrated %v", paths.MigratedFrom, tt.migrated)
+			}
+			if !tt.migrated {
+				for name, contents := range tt.legacy {
+					if got := readTestFile(t, filepath.Join(legacy, name)); got != contents {
+						t.Errorf("legacy %s = %q, want %q", name, got, contents)
+					}
+				}
+				return
+			}
+
+			for name, contents := range tt.legacy {
+				if got := readTestFile(t, filepath.Join(paths.Dir, name)); got != contents {
+					t.Errorf("%s = %q, want %q", name, got, contents)
+				}
+			}
+			entries, err := os.ReadDir(legacy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !isNoticeOnly(entries) {
+				t.Errorf("the legacy directory holds %d entries rather than only the notice", len(entries))
+			}
+			if notice := readTestFile(t, filepath.Join(legacy, legacyNoticeFile)); !strings.Contains(notice, paths.Dir) {
+				t.Errorf("the notice does not name %s: %q", paths.Dir, notice)
+			}
+		})
+	}
+}
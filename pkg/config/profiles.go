@@ -0,0 +1,149 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+)
+
+var profileNameRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+var (
+	ErrInvalidProfile  = errors.New("profile names may contain only letters, digits, '.', '_' and '-', and must start with a letter or digit")
+	ErrProfileNotFound = errors.New("the profile does not exist")
+	ErrProfileExists   = errors.New("a profile with that name already exists")
+	ErrProfileInUse    = errors.New("the active profile cannot be deleted")
+)
+
+func (p *Paths) Profiles() ([]string, error) {
+	cfg, err := p.ReadConfig()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (p *Paths) UseProfile(name string) (*Paths, error) {
+	cfg, err := p.ReadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := cfg.Profiles[name]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+	return p.ForProfile(name), nil
+}
+
+func (p *Paths) SetDefaultProfile(name string) error {
+	unlock, err := p.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cfg, err := p.ReadConfig()
+	if err != nil {
+		return err
+	}
+	if _, ok := cfg.Profiles[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+	cfg.DefaultProfile = name
+	return p.WriteConfig(cfg)
+}
+
+func (p *Paths) CreateProfile(name string) error {
+	if !profileNameRegex.MatchString(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidProfile, name)
+	}
+	unlock, err := p.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cfg, err := p.ReadConfig()
+	if err != nil {
+		return err
+	}
+	if _, ok := cfg.Profiles[name]; ok {
+		return fmt.Errorf("%w: %s", ErrProfileExists, name)
+	}
+	if err := p.ForProfile(name).Initialise(); err != nil {
+		return err
+	}
+	cfg.Profile(name)
+	return p.WriteConfig(cfg)
+}
+
+func (p *Paths) RenameProfile(from, to string) error {
+	if !profileNameRegex.MatchString(to) {
+		return fmt.Errorf("%w: %q", ErrInvalidProfile, to)
+	}
+	unlock, err := p.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cfg, err := p.ReadConfig()
+	if err != nil {
+		return err
+	}
+	profile, ok := cfg.Profiles[from]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrProfileNotFound, from)
+	}
+	if _, ok := cfg.Profiles[to]; ok {
+		return fmt.Errorf("%w: %s", ErrProfileExists, to)
+	}
+
+	source, target := p.ForProfile(from), p.ForProfile(to)
+	if _, err := os.Stat(source.ProfileDir); err == nil {
+		if err := os.MkdirAll(p.Dir, 0700); err != nil {
+			return err
+		}
+		if err := os.Rename(source.ProfileDir, target.ProfileDir); err != nil {
+			return err
+		}
+	}
+
+	delete(cfg.Profiles, from)
+	cfg.Profiles[to] = profile
+	if cfg.DefaultProfile == from {
+		cfg.DefaultProfile = to
+	}
+	return p.WriteConfig(cfg)
+}
+
+func (p *Paths) DeleteProfile(name string) error {
+	unlock, err := p.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cfg, err := p.ReadConfig()
+	if err != nil {
+		return err
+	}
+	if _, ok := cfg.Profiles[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+	if name == cfg.DefaultProfile || name == p.Profile {
+		return fmt.Errorf("%w: %s", ErrProfileInUse, name)
+	}
+
+	if _, err := WipeDirectory(p.ForProfile(name).ProfileDir); err != nil {
+		return err
+	}
+	delete(cfg.Profiles, name)
+	return p.WriteConfig(cfg)
+}
@@ -1,39 +1,100 @@
 package discord
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
+const (
+	ConnectionTypeMastodon = "mastodon"
+
+	VisibilityOnlyMe   = 0
+	VisibilityEveryone = 1
+)
+
+const (
+	DefaultBaseURL   = "https://discord.com/api/v9"
+	DefaultUserAgent = "fediscord"
+	DefaultTimeout   = 20 * time.Second
+)
+
+var (
+	ErrUnauthorized = errors.New("the Discord API rejected the supplied token")
+	ErrForbidden    = errors.New("the Discord account lacks the permissions or verification required for this request")
+	ErrBadRequest   = errors.New("the Discord API rejected the request parameters")
+	ErrRateLimited  = errors.New("the Discord API rate limit has been exceeded")
+	ErrUnavailable  = errors.New("the Discord API is currently unavailable")
+)
+
+type Client struct {
+	BaseURL    string
+	Token      string
+	UserAgent  string
+	HTTPClient *http.Client
+}
+
+type User struct {
+	ID            string `json:"id"`
+	Username      string `json:"username"`
+	GlobalName    string `json:"global_name,omitempty"`
+	Discriminator string `json:"discriminator,omitempty"`
+}
+
+type Connection struct {
+	Type         string `json:"type"`
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Verified     bool   `json:"verified"`
+	Revoked      bool   `json:"revoked"`
+	Visibility   int    `json:"visibility"`
+	ShowActivity bool   `json:"show_activity"`
+	FriendSync   bool   `json:"friend_sync"`
+}
+
+type ConnectionUpdate struct {
+	Visibility   *int  `json:"visibility,omitempty"`
+	ShowActivity *bool `json:"show_activity,omitempty"`
+}
+
 type authorisationResponse struct {
 	URL string `json:"url"`
 }
 
-func GenerateConnectionURL(handle, token string) (string, error) {
-	encodedHandle := url.QueryEscape("@" + handle)
-	endpoint := fmt.Sprintf("https://discord.com/api/v9/connections/mastodon/authorize?handle=%s", encodedHandle)
+type Option func(*RetryTransport)
 
-	client := &http.Client{Timeout: 15 * time.Second}
-	req, err := http.NewRequest("GET", endpoint, nil)
-	if err != nil {
-		return "", fmt.Errorf("the HTTP request could not be constructed: %w", err)
+func OnRetry(handler func(RetryEvent)) Option {
+	return func(t *RetryTransport) {
+		t.OnRetry = handler
 	}
-	req.Header.Set("authorization", token)
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("the request to the Discord API endpoint was unsuccessful: %w", err)
+func NewClient(token string, options ...Option) *Client {
+	transport := NewRetryTransport(http.DefaultTransport, nil)
+	for _, option := range options {
+		option(transport)
 	}
-	defer resp.Body.Close()
+	return &Client{
+		BaseURL:    DefaultBaseURL,
+		Token:      token,
+		UserAgent:  DefaultUserAgent,
+		HTTPClient: &http.Client{Transport: transport},
+	}
+}
 
-	body, err := io.ReadAll(resp.Body)
+func (c *Client) GenerateConnectionURL(ctx context.Context, handle string) (string, error) {
+	query := url.Values{"handle": {"@" + handle}}
+
+	body, err := c.get(ctx, "/connections/mastodon/authorize", query)
 	if err != nil {
-		return "", fmt.Errorf("the response body could not be read: %w", err)
+		return "", err
 	}
 
 	var result authorisationResponse
@@ -47,3 +108,128 @@ func GenerateConnectionURL(handle, token string) (string, error) {
 
 	return result.URL, nil
 }
+
+func (c *Client) CurrentUser(ctx context.Context) (*User, error) {
+	body, err := c.get(ctx, "/users/@me", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var user User
+	if err := json.Unmarshal(body, &user); err != nil {
+		return nil, fmt.Errorf("the Discord API response could not be parsed: %w", err)
+	}
+	if user.ID == "" {
+		return nil, errors.New("the Discord API did not return the current user")
+	}
+	return &user, nil
+}
+
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.get(ctx, "/gateway", nil)
+	return err
+}
+
+func (c *Client) ListConnections(ctx context.Context) ([]Connection, error) {
+	body, err := c.get(ctx, "/users/@me/connections", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var connections []Connection
+	if err := json.Unmarshal(body, &connections); err != nil {
+		return nil, fmt.Errorf("the Discord API response could not be parsed: %w", err)
+	}
+	return connections, nil
+}
+
+func (conn *Connection) Matches(handle string) bool {
+	return conn.Type == ConnectionTypeMastodon &&
+		strings.EqualFold(strings.TrimPrefix(conn.Name, "@"), strings.TrimPrefix(handle, "@"))
+}
+
+func (u *User) Tag() string {
+	if u.Discriminator != "" && u.Discriminator != "0" {
+		return u.Username + "#" + u.Discriminator
+	}
+	return u.Username
+}
+
+func (c *Client) DeleteConnection(ctx context.Context, connType, id string) error {
+	_, err := c.do(ctx, http.MethodDelete, connectionPath(connType, id), nil, nil)
+	return err
+}
+
+func (c *Client) UpdateConnection(ctx context.Context, connType, id string, update ConnectionUpdate) (*Connection, error) {
+	body, err := c.do(ctx, http.MethodPatch, connectionPath(connType, id), nil, update)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn Connection
+	if err := json.Unmarshal(body, &conn); err != nil {
+		return nil, fmt.Errorf("the Discord API response could not be parsed: %w", err)
+	}
+	return &conn, nil
+}
+
+func connectionPath(connType, id string) string {
+	return "/users/@me/connections/" + url.PathEscape(connType) + "/" + url.PathEscape(id)
+}
+
+func (c *Client) get(ctx context.Context, path string, query url.Values) ([]byte, error) {
+	return c.do(ctx, http.MethodGet, path, query, nil)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, payload any) ([]byte, error) {
+	endpoint := strings.TrimSuffix(c.BaseURL, "/") + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var reqBody io.Reader
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("the request body could not be encoded: %w", err)
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("the HTTP request could not be constructed: %w", err)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", c.Token)
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("the request to the Discord API endpoint was unsuccessful: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("the response body could not be read: %w", err)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, newAPIError(resp, body)
+	}
+	return body, nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
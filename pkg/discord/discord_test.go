@@ -0,0 +1,352 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type recordedRequest struct {
+	method        string
+	path          string
+	query         string
+	authorization string
+	contentType   string
+	body          string
+}
+
+func recordingServer(t *testing.T, status int, body string) (*Client, *recordedRequest) {
+	t.Helper()
+	recorded := &recordedRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		*recorded = recordedRequest{
+			method:        r.Method,
+			path:          r.URL.EscapedPath(),
+			query:         r.URL.RawQuery,
+			authorization: r.Header.Get("Authorization"),
+			contentType:   r.Header.Get("Content-Type"),
+			body:          string(data),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient("test-token")
+	client.BaseURL = server.URL + "/api/v9"
+	client.HTTPClient = &http.Client{Transport: testTransport(nil)}
+	return client, recorded
+}
+
+func (r *recordedRequest) check(t *testing.T, method, path, body string) {
+	t.Helper()
+	if r.method != method || r.path != path {
+		t.Errorf("request = %s %s, want %s %s", r.method, r.path, method, path)
+	}
+	if r.authorization != "test-token" {
+		t.Errorf("Authorization = %q, want %q", r.authorization, "test-token")
+	}
+	if r.body != body {
+		t.Errorf("body = %q, want %q", r.body, body)
+	}
+	if body != "" && r.contentType != "application/json" {
+		t.Errorf("Content-Type = %q, want %q", r.contentType, "application/json")
+	}
+}
+
+func TestCurrentUser(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want *User
+		tag  string
+	}{
+		{
+			name: "unique username",
+			body: `{"id": "80351110224678912", "username": "alice", "global_name": "Alice", "discriminator": "0"}`,
+			want: &User{ID: "80351110224678912", Username: "alice", GlobalName: "Alice", Discriminator: "0"},
+			tag:  "alice",
+		},
+		{
+			name: "legacy discriminator",
+			body: `{"id": "80351110224678912", "username": "alice", "discriminator": "1337"}`,
+			want: &User{ID: "80351110224678912", Username: "alice", Discriminator: "1337"},
+			tag:  "alice#1337",
+		},
+		{
+			name: "missing id",
+			body: `{"username": "alice"}`,
+		},
+		{
+			name: "malformed",
+			body: `<html></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, recorded := recordingServer(t, http.StatusOK, tt.body)
+			got, err := client.CurrentUser(context.Background())
+			recorded.check(t, http.MethodGet, "/api/v9/users/@me", "")
+			if tt.want == nil {
+				if err == nil {
+					t.Errorf("CurrentUser() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CurrentUser failed: %v", err)
+			}
+			if *got != *tt.want {
+				t.Errorf("CurrentUser() = %+v, want %+v", *got, *tt.want)
+			}
+			if tag := got.Tag(); tag != tt.tag {
+				t.Errorf("Tag() = %q, want %q", tag, tt.tag)
+			}
+		})
+	}
+}
+
+func TestGenerateConnectionURL(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "authorisation url",
+			body: `{"url": "https://example.social/oauth/authorize?client_id=discord"}`,
+			want: "https://example.social/oauth/authorize?client_id=discord",
+		},
+		{
+			name: "empty url",
+			body: `{"url": ""}`,
+		},
+		{
+			name: "malformed",
+			body: `[]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, recorded := recordingServer(t, http.StatusOK, tt.body)
+			got, err := client.GenerateConnectionURL(context.Background(), "alice@example.social")
+			recorded.check(t, http.MethodGet, "/api/v9/connections/mastodon/authorize", "")
+			if recorded.query != "handle=%40alice%40example.social" {
+				t.Errorf("query = %q, want %q", recorded.query, "handle=%40alice%40example.social")
+			}
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("GenerateConnectionURL() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GenerateConnectionURL failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GenerateConnectionURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientAPIErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   error
+	}{
+		{http.StatusUnauthorized, `{"message": "401: Unauthorized", "code": 0}`, ErrUnauthorized},
+		{http.StatusForbidden, `{"message": "You need to verify your account in order to perform this action.", "code": 40002}`, ErrForbidden},
+		{http.StatusBadRequest, `{"message": "Invalid Form Body", "code": 50035}`, ErrBadRequest},
+		{http.StatusInternalServerError, ``, ErrUnavailable},
+		{http.StatusBadGateway, `<html>Bad Gateway</html>`, ErrUnavailable},
+		{http.StatusNotFound, `{"message": "Unknown Connection", "code": 10017}`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			client, _ := recordingServer(t, tt.status, tt.body)
+			_, err := client.CurrentUser(context.Background())
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("CurrentUser() error = %v, want an APIError", err)
+			}
+			if apiErr.StatusCode != tt.status {
+				t.Errorf("StatusCode = %d, want %d", apiErr.StatusCode, tt.status)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("CurrentUser() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientWithoutToken(t *testing.T) {
+	client, recorded := recordingServer(t, http.StatusOK, `{"url": "wss://gateway.discord.gg"}`)
+	client.Token = ""
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if recorded.path != "/api/v9/gateway" {
+		t.Errorf("path = %q, want %q", recorded.path, "/api/v9/gateway")
+	}
+	if recorded.authorization != "" {
+		t.Errorf("Authorization = %q, want none", recorded.authorization)
+	}
+}
+
+func TestListConnections(t *testing.T) {
+	client, recorded := recordingServer(t, http.StatusOK, `[
+		{"type": "mastodon", "id": "109", "name": "@alice@example.social", "verified": true, "visibility": 1, "show_activity": true},
+		{"type": "github", "id": "42", "name": "alice", "verified": true, "revoked": true, "friend_sync": true}
+	]`)
+	got, err := client.ListConnections(context.Background())
+	if err != nil {
+		t.Fatalf("ListConnections failed: %v", err)
+	}
+	recorded.check(t, http.MethodGet, "/api/v9/users/@me/connections", "")
+
+	want := []Connection{
+		{Type: "mastodon", ID: "109", Name: "@alice@example.social", Verified: true, Visibility: VisibilityEveryone, ShowActivity: true},
+		{Type: "github", ID: "42", Name: "alice", Verified: true, Revoked: true, FriendSync: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListConnections() = %+v, want %+v", got, want)
+	}
+
+	client, _ = recordingServer(t, http.StatusOK, `{"type": "mastodon"}`)
+	if _, err := client.ListConnections(context.Background()); err == nil {
+		t.Error("ListConnections of a malformed response succeeded")
+	}
+	client, _ = recordingServer(t, http.StatusUnauthorized, `{"message": "401: Unauthorized", "code": 0}`)
+	if _, err := client.ListConnections(context.Background()); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("ListConnections() error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestConnectionMatches(t *testing.T) {
+	tests := []struct {
+		conn   Connection
+		handle string
+		want   bool
+	}{
+		{Connection{Type: "mastodon", Name: "@alice@example.social"}, "alice@example.social", true},
+		{Connection{Type: "mastodon", Name: "alice@example.social"}, "@alice@example.social", true},
+		{Connection{Type: "mastodon", Name: "@Alice@Example.Social"}, "alice@example.social", true},
+		{Connection{Type: "mastodon", Name: "@alice@other.example"}, "alice@example.social", false},
+		{Connection{Type: "github", Name: "alice@example.social"}, "alice@example.social", false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.conn.Matches(tt.handle); got != tt.want {
+			t.Errorf("%+v.Matches(%q) = %v, want %v", tt.conn, tt.handle, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateConnection(t *testing.T) {
+	visibility := VisibilityOnlyMe
+	showActivity := false
+	tests := []struct {
+		name   string
+		id     string
+		update ConnectionUpdate
+		path   string
+		body   string
+	}{
+		{
+			name:   "visibility",
+			id:     "109",
+			update: ConnectionUpdate{Visibility: &visibility},
+			path:   "/api/v9/users/@me/connections/mastodon/109",
+			body:   `{"visibility":0}`,
+		},
+		{
+			name:   "activity",
+			id:     "109",
+			update: ConnectionUpdate{ShowActivity: &showActivity},
+			path:   "/api/v9/users/@me/connections/mastodon/109",
+			body:   `{"show_activity":false}`,
+		},
+		{
+			name:   "both",
+			id:     "109",
+			update: ConnectionUpdate{Visibility: &visibility, ShowActivity: &showActivity},
+			path:   "/api/v9/users/@me/connections/mastodon/109",
+			body:   `{"visibility":0,"show_activity":false}`,
+		},
+		{
+			name:   "escaped id",
+			id:     "alice/example.social",
+			update: ConnectionUpdate{Visibility: &visibility},
+			path:   "/api/v9/users/@me/connections/mastodon/alice%2Fexample.social",
+			body:   `{"visibility":0}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, recorded := recordingServer(t, http.StatusOK, `{"type": "mastodon", "id": "109", "name": "@alice@example.social", "visibility": 0}`)
+			got, err := client.UpdateConnection(context.Background(), ConnectionTypeMastodon, tt.id, tt.update)
+			if err != nil {
+				t.Fatalf("UpdateConnection failed: %v", err)
+			}
+			recorded.check(t, http.MethodPatch, tt.path, tt.body)
+			want := Connection{Type: "mastodon", ID: "109", Name: "@alice@example.social", Visibility: VisibilityOnlyMe}
+			if *got != want {
+				t.Errorf("UpdateConnection() = %+v, want %+v", *got, want)
+			}
+		})
+	}
+
+	client, _ := recordingServer(t, http.StatusBadRequest, `{"message": "Invalid Form Body", "code": 50035, "errors": {"visibility": {"_errors": [{"code": "NUMBER_TYPE_MAX", "message": "int value should be less than or equal to 1."}]}}}`)
+	_, err := client.UpdateConnection(context.Background(), ConnectionTypeMastodon, "109", ConnectionUpdate{Visibility: &visibility})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("UpdateConnection() error = %v, want ErrBadRequest", err)
+	}
+	if fields := apiErr.FieldErrors(); len(fields) != 1 {
+		t.Errorf("FieldErrors() = %q, want one field", fields)
+	}
+}
+
+func TestDeleteConnection(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		want   error
+	}{
+		{name: "deleted", status: http.StatusNoContent},
+		{name: "unauthorised", status: http.StatusUnauthorized, want: ErrUnauthorized},
+		{name: "forbidden", status: http.StatusForbidden, want: ErrForbidden},
+		{name: "unavailable", status: http.StatusServiceUnavailable, want: ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, recorded := recordingServer(t, tt.status, "")
+			err := client.DeleteConnection(context.Background(), ConnectionTypeMastodon, "109")
+			recorded.check(t, http.MethodDelete, "/api/v9/users/@me/connections/mastodon/109", "")
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("DeleteConnection failed: %v", err)
+				}
+				return
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status || !errors.Is(err, tt.want) {
+				t.Errorf("DeleteConnection() error = %v, want an APIError wrapping %v", err, tt.want)
+			}
+		})
+	}
+}
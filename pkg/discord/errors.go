@@ -0,0 +1,117 @@
+package discord
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type APIError struct {
+	StatusCode int             `json:"-"`
+	Code       int             `json:"code"`
+	Message    string          `json:"message"`
+	Errors     json.RawMessage `json:"errors,omitempty"`
+	RetryAfter float64         `json:"retry_after,omitempty"`
+}
+
+type fieldError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{}
+	if err := json.Unmarshal(body, apiErr); err != nil || apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	apiErr.StatusCode = resp.StatusCode
+
+	if apiErr.RetryAfter == 0 {
+		if seconds, err := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64); err == nil {
+			apiErr.RetryAfter = seconds
+		}
+	}
+	return apiErr
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	if sentinel := e.Unwrap(); sentinel != nil {
+		b.WriteString(sentinel.Error())
+	} else {
+		b.WriteString("the Discord API returned an error")
+	}
+	fmt.Fprintf(&b, " (HTTP %d", e.StatusCode)
+	if e.Code != 0 {
+		fmt.Fprintf(&b, ", code %d", e.Code)
+	}
+	fmt.Fprintf(&b, ": %s)", e.Message)
+	if fields := e.FieldErrors(); len(fields) > 0 {
+		b.WriteString("; " + strings.Join(fields, "; "))
+	}
+	return b.String()
+}
+
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusBadRequest:
+		return ErrBadRequest
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusForbidden:
+		return ErrForbidden
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode >= http.StatusInternalServerError:
+		return ErrUnavailable
+	default:
+		return nil
+	}
+}
+
+func (e *APIError) RetryAfterDuration() time.Duration {
+	return seconds(e.RetryAfter)
+}
+
+func (e *APIError) FieldErrors() []string {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	var tree map[string]json.RawMessage
+	if err := json.Unmarshal(e.Errors, &tree); err != nil {
+		return nil
+	}
+	var fields []string
+	collectFieldErrors("", tree, &fields)
+	sort.Strings(fields)
+	return fields
+}
+
+func collectFieldErrors(path string, tree map[string]json.RawMessage, fields *[]string) {
+	for key, raw := range tree {
+		if key == "_errors" {
+			var list []fieldError
+			if err := json.Unmarshal(raw, &list); err != nil {
+				continue
+			}
+			for _, item := range list {
+				*fields = append(*fields, path+": "+item.Message)
+			}
+			continue
+		}
+
+		var child map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &child); err != nil {
+			continue
+		}
+		next := key
+		if path != "" {
+			next = path + "." + key
+		}
+		collectFieldErrors(next, child, fields)
+	}
+}
@@ -0,0 +1,175 @@
+package discord
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIErrorUnwrap(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusBadRequest, ErrBadRequest},
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusForbidden, ErrForbidden},
+		{http.StatusTooManyRequests, ErrRateLimited},
+		{http.StatusInternalServerError, ErrUnavailable},
+		{http.StatusBadGateway, ErrUnavailable},
+		{http.StatusServiceUnavailable, ErrUnavailable},
+		{http.StatusNotFound, nil},
+		{http.StatusConflict, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			apiErr := &APIError{StatusCode: tt.status, Message: "failure"}
+			if got := apiErr.Unwrap(); got != tt.want {
+				t.Errorf("Unwrap() = %v, want %v", got, tt.want)
+			}
+			if tt.want != nil && !errors.Is(apiErr, tt.want) {
+				t.Errorf("errors.Is(%v, %v) = false", apiErr, tt.want)
+			}
+			var target *APIError
+			if !errors.As(error(apiErr), &target) || target.StatusCode != tt.status {
+				t.Errorf("errors.As did not recover the APIError")
+			}
+		})
+	}
+}
+
+func TestNewAPIError(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		header     http.Header
+		body       string
+		code       int
+		message    string
+		retryAfter time.Duration
+	}{
+		{
+			name:    "json body",
+			status:  http.StatusUnauthorized,
+			body:    `{"code": 0, "message": "401: Unauthorized"}`,
+			message: "401: Unauthorized",
+		},
+		{
+			name:    "discord code",
+			status:  http.StatusForbidden,
+			body:    `{"code": 40002, "message": "You need to verify your account in order to perform this action."}`,
+			code:    40002,
+			message: "You need to verify your account in order to perform this action.",
+		},
+		{
+			name:    "html body",
+			status:  http.StatusBadGateway,
+			body:    `<html>Bad Gateway</html>`,
+			message: "Bad Gateway",
+		},
+		{
+			name:    "empty body",
+			status:  http.StatusServiceUnavailable,
+			message: "Service Unavailable",
+		},
+		{
+			name:       "retry after in body",
+			status:     http.StatusTooManyRequests,
+			header:     http.Header{"Retry-After": {"9"}},
+			body:       `{"message": "You are being rate limited.", "retry_after": 1.5, "global": false}`,
+			message:    "You are being rate limited.",
+			retryAfter: 1500 * time.Millisecond,
+		},
+		{
+			name:       "retry after in header",
+			status:     http.StatusTooManyRequests,
+			header:     http.Header{"Retry-After": {"3"}},
+			message:    "Too Many Requests",
+			retryAfter: 3 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: tt.header}
+			if resp.Header == nil {
+				resp.Header = http.Header{}
+			}
+			apiErr := newAPIError(resp, []byte(tt.body))
+			if apiErr.StatusCode != tt.status {
+				t.Errorf("StatusCode = %d, want %d", apiErr.StatusCode, tt.status)
+			}
+			if apiErr.Code != tt.code {
+				t.Errorf("Code = %d, want %d", apiErr.Code, tt.code)
+			}
+			if apiErr.Message != tt.message {
+				t.Errorf("Message = %q, want %q", apiErr.Message, tt.message)
+			}
+			if got := apiErr.RetryAfterDuration(); got != tt.retryAfter {
+				t.Errorf("RetryAfterDuration() = %v, want %v", got, tt.retryAfter)
+			}
+		})
+	}
+}
+
+func TestAPIErrorFieldErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		errors string
+		want   []string
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:   "top-level field",
+			errors: `{"visibility": {"_errors": [{"code": "NUMBER_TYPE_MAX", "message": "int value should be less than or equal to 1."}]}}`,
+			want:   []string{"visibility: int value should be less than or equal to 1."},
+		},
+		{
+			name: "nested and multiple fields",
+			errors: `{
+				"show_activity": {"_errors": [{"code": "BOOLEAN_TYPE_COERCE", "message": "Must be either true or false."}]},
+				"metadata": {"0": {"key": {"_errors": [
+					{"code": "BASE_TYPE_REQUIRED", "message": "This field is required"},
+					{"code": "BASE_TYPE_MAX_LENGTH", "message": "Must be 50 or fewer in length."}
+				]}}}
+			}`,
+			want: []string{
+				"metadata.0.key: Must be 50 or fewer in length.",
+				"metadata.0.key: This field is required",
+				"show_activity: Must be either true or false.",
+			},
+		},
+		{
+			name:   "malformed",
+			errors: `["unexpected"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := &APIError{StatusCode: http.StatusBadRequest, Code: 50035, Message: "Invalid Form Body"}
+			if tt.errors != "" {
+				apiErr.Errors = []byte(tt.errors)
+			}
+			got := apiErr.FieldErrors()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FieldErrors() = %q, want %q", got, tt.want)
+			}
+			message := apiErr.Error()
+			if !strings.HasPrefix(message, ErrBadRequest.Error()+" (HTTP 400, code 50035: Invalid Form Body)") {
+				t.Errorf("Error() = %q", message)
+			}
+			for _, field := range tt.want {
+				if !strings.Contains(message, field) {
+					t.Errorf("Error() = %q does not mention %q", message, field)
+				}
+			}
+		})
+	}
+}
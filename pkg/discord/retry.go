@@ -0,0 +1,199 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultMaxAttempts  = 4
+	DefaultBaseDelay    = 500 * time.Millisecond
+	DefaultMaxDelay     = 8 * time.Second
+	DefaultMaxRateLimit = 30 * time.Second
+)
+
+type RetryEvent struct {
+	Attempt     int
+	MaxAttempts int
+	Wait        time.Duration
+	Reason      string
+}
+
+type RetryTransport struct {
+	Base         http.RoundTripper
+	MaxAttempts  int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	MaxRateLimit time.Duration
+	Timeout      time.Duration
+	OnRetry      func(RetryEvent)
+}
+
+func NewRetryTransport(base http.RoundTripper, onRetry func(RetryEvent)) *RetryTransport {
+	return &RetryTransport{
+		Base:         base,
+		MaxAttempts:  DefaultMaxAttempts,
+		BaseDelay:    DefaultBaseDelay,
+		MaxDelay:     DefaultMaxDelay,
+		MaxRateLimit: DefaultMaxRateLimit,
+		Timeout:      DefaultTimeout,
+		OnRetry:      onRetry,
+	}
+}
+
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	maxAttempts := t.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := t.attempt(req, attempt)
+		if attempt >= maxAttempts || req.Context().Err() != nil || !replayable(req) {
+			return resp, err
+		}
+
+		wait, reason, retry := t.evaluate(req, resp, err, attempt)
+		if !retry {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if t.OnRetry != nil {
+			t.OnRetry(RetryEvent{Attempt: attempt, MaxAttempts: maxAttempts, Wait: wait, Reason: reason})
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (t *RetryTransport) attempt(req *http.Request, attempt int) (*http.Response, error) {
+	ctx, cancel := req.Context(), context.CancelFunc(func() {})
+	if t.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, t.Timeout)
+	}
+
+	clone := req.Clone(ctx)
+	if attempt > 1 && req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		clone.Body = body
+	}
+
+	resp, err := t.base().RoundTrip(clone)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+func replayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func (t *RetryTransport) evaluate(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, string, bool) {
+	if err != nil {
+		if !isIdempotent(req.Method) {
+			return 0, "", false
+		}
+		return t.backoff(attempt), "network error: " + err.Error(), true
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		wait, ok := rateLimitWait(resp)
+		if !ok {
+			wait = t.backoff(attempt)
+		}
+		if t.MaxRateLimit > 0 && wait > t.MaxRateLimit {
+			return 0, "", false
+		}
+		return wait, fmt.Sprintf("rate limited (HTTP %d)", resp.StatusCode), true
+	case resp.StatusCode >= http.StatusInternalServerError && isIdempotent(req.Method):
+		return t.backoff(attempt), fmt.Sprintf("server error (HTTP %d)", resp.StatusCode), true
+	default:
+		return 0, "", false
+	}
+}
+
+func (t *RetryTransport) backoff(attempt int) time.Duration {
+	delay := t.BaseDelay << (attempt - 1)
+	if t.MaxDelay > 0 && (delay > t.MaxDelay || delay <= 0) {
+		delay = t.MaxDelay
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func (t *RetryTransport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+func rateLimitWait(resp *http.Response) (time.Duration, bool) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err == nil {
+		var payload struct {
+			RetryAfter float64 `json:"retry_after"`
+		}
+		if json.Unmarshal(body, &payload) == nil && payload.RetryAfter > 0 {
+			return seconds(payload.RetryAfter), true
+		}
+	}
+
+	for _, header := range []string{"Retry-After", "X-RateLimit-Reset-After"} {
+		if value, err := strconv.ParseFloat(resp.Header.Get(header), 64); err == nil && value > 0 {
+			return seconds(value), true
+		}
+	}
+	return 0, false
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
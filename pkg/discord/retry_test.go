@@ -0,0 +1,366 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type scriptedResponse struct {
+	status int
+	header http.Header
+	body   string
+	delay  time.Duration
+}
+
+func scriptedServer(t *testing.T, script []scriptedResponse, bodies *[]string) (*httptest.Server, *int32) {
+	t.Helper()
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1)) - 1
+		if bodies != nil {
+			body, _ := io.ReadAll(r.Body)
+			*bodies = append(*bodies, string(body))
+		}
+		step := script[len(script)-1]
+		if n < len(script) {
+			step = script[n]
+		}
+		if step.delay > 0 {
+			select {
+			case <-time.After(step.delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		for key, values := range step.header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(step.status)
+		io.WriteString(w, step.body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func testTransport(events *[]RetryEvent) *RetryTransport {
+	transport := NewRetryTransport(http.DefaultTransport, func(event RetryEvent) {
+		if events != nil {
+			*events = append(*events, event)
+		}
+	})
+	transport.BaseDelay = time.Millisecond
+	transport.MaxDelay = 5 * time.Millisecond
+	transport.MaxRateLimit = time.Second
+	return transport
+}
+
+func TestRetryTransport(t *testing.T) {
+	ok := scriptedResponse{status: http.StatusOK, body: `{}`}
+	tests := []struct {
+		name     string
+		method   string
+		script   []scriptedResponse
+		status   int
+		calls    int
+		retries  int
+		wait     time.Duration
+		reason   string
+		maxLimit time.Duration
+	}{
+		{
+			name:    "success",
+			method:  http.MethodGet,
+			script:  []scriptedResponse{ok},
+			status:  http.StatusOK,
+			calls:   1,
+			retries: 0,
+		},
+		{
+			name:   "rate limited with Retry-After",
+			method: http.MethodGet,
+			script: []scriptedResponse{
+				{status: http.StatusTooManyRequests, header: http.Header{"Retry-After": {"0.02"}}},
+				ok,
+			},
+			status:  http.StatusOK,
+			calls:   2,
+			retries: 1,
+			wait:    20 * time.Millisecond,
+			reason:  "rate limited (HTTP 429)",
+		},
+		{
+			name:   "rate limited with retry_after body",
+			method: http.MethodPost,
+			script: []scriptedResponse{
+				{status: http.StatusTooManyRequests, header: http.Header{"Retry-After": {"5"}}, body: `{"message": "You are being rate limited.", "retry_after": 0.01}`},
+				ok,
+			},
+			status:  http.StatusOK,
+			calls:   2,
+			retries: 1,
+			wait:    10 * time.Millisecond,
+			reason:  "rate limited (HTTP 429)",
+		},
+		{
+			name:   "rate limited with X-RateLimit-Reset-After",
+			method: http.MethodPatch,
+			script: []scriptedResponse{
+				{status: http.StatusTooManyRequests, header: http.Header{"X-Ratelimit-Reset-After": {"0.015"}}},
+				ok,
+			},
+			status:  http.StatusOK,
+			calls:   2,
+			retries: 1,
+			wait:    15 * time.Millisecond,
+			reason:  "rate limited (HTTP 429)",
+		},
+		{
+			name:   "rate limit longer than allowed",
+			method: http.MethodGet,
+			script: []scriptedResponse{
+				{status: http.StatusTooManyRequests, header: http.Header{"Retry-After": {"120"}}},
+				ok,
+			},
+			status:  http.StatusTooManyRequests,
+			calls:   1,
+			retries: 0,
+		},
+		{
+			name:   "server error on idempotent request",
+			method: http.MethodGet,
+			script: []scriptedResponse{
+				{status: http.StatusBadGateway},
+				{status: http.StatusServiceUnavailable},
+				ok,
+			},
+			status:  http.StatusOK,
+			calls:   3,
+			retries: 2,
+			reason:  "server error (HTTP 503)",
+		},
+		{
+			name:   "server error on non-idempotent request",
+			method: http.MethodPost,
+			script: []scriptedResponse{
+				{status: http.StatusInternalServerError},
+				ok,
+			},
+			status:  http.StatusInternalServerError,
+			calls:   1,
+			retries: 0,
+		},
+		{
+			name:    "gives up after the last attempt",
+			method:  http.MethodDelete,
+			script:  []scriptedResponse{{status: http.StatusServiceUnavailable}},
+			status:  http.StatusServiceUnavailable,
+			calls:   DefaultMaxAttempts,
+			retries: DefaultMaxAttempts - 1,
+			reason:  "server error (HTTP 503)",
+		},
+		{
+			name:    "client error",
+			method:  http.MethodGet,
+			script:  []scriptedResponse{{status: http.StatusUnauthorized}, ok},
+			status:  http.StatusUnauthorized,
+			calls:   1,
+			retries: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := scriptedServer(t, tt.script, nil)
+			var events []RetryEvent
+			client := &http.Client{Transport: testTransport(&events)}
+
+			req, err := http.NewRequest(tt.method, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if got := int(atomic.LoadInt32(calls)); got != tt.calls {
+				t.Errorf("server received %d requests, want %d", got, tt.calls)
+			}
+			if len(events) != tt.retries {
+				t.Fatalf("OnRetry called %d times, want %d", len(events), tt.retries)
+			}
+			for i, event := range events {
+				if event.Attempt != i+1 || event.MaxAttempts != DefaultMaxAttempts {
+					t.Errorf("event %d = attempt %d of %d", i, event.Attempt, event.MaxAttempts)
+				}
+			}
+			if tt.retries == 0 {
+				return
+			}
+			last := events[len(events)-1]
+			if tt.reason != "" && last.Reason != tt.reason {
+				t.Errorf("Reason = %q, want %q", last.Reason, tt.reason)
+			}
+			if tt.wait != 0 && last.Wait != tt.wait {
+				t.Errorf("Wait = %v, want %v", last.Wait, tt.wait)
+			}
+		})
+	}
+}
+
+func TestRetryTransportReplaysBody(t *testing.T) {
+	payload := `{"visibility":1}`
+	var bodies []string
+	server, _ := scriptedServer(t, []scriptedResponse{
+		{status: http.StatusTooManyRequests, body: `{"retry_after": 0.001}`},
+		{status: http.StatusTooManyRequests, body: `{"retry_after": 0.001}`},
+		{status: http.StatusOK, body: `{}`},
+	}, &bodies)
+
+	req, err := http.NewRequest(http.MethodPatch, server.URL, strings.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := req.Body
+
+	resp, err := (&http.Client{Transport: testTransport(nil)}).Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if len(bodies) != 3 {
+		t.Fatalf("server received %d requests, want 3", len(bodies))
+	}
+	for i, body := range bodies {
+		if body != payload {
+			t.Errorf("attempt %d sent body %q, want %q", i+1, body, payload)
+		}
+	}
+	if req.Body != original {
+		t.Errorf("the caller's request body was replaced")
+	}
+}
+
+type onceReader struct {
+	io.Reader
+}
+
+func TestRetryTransportWithoutGetBody(t *testing.T) {
+	server, calls := scriptedServer(t, []scriptedResponse{
+		{status: http.StatusTooManyRequests, body: `{"retry_after": 0.001}`},
+		{status: http.StatusOK},
+	}, nil)
+
+	req, err := http.NewRequest(http.MethodPost, server.URL, onceReader{bytes.NewReader([]byte(`{}`))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.GetBody != nil {
+		t.Fatal("GetBody was set for an opaque reader")
+	}
+
+	resp, err := (&http.Client{Transport: testTransport(nil)}).Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusTooManyRequests)
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("server received %d requests, want 1", got)
+	}
+}
+
+func TestRetryTransportTimesOutEachAttempt(t *testing.T) {
+	server, calls := scriptedServer(t, []scriptedResponse{
+		{status: http.StatusOK, delay: time.Second},
+		{status: http.StatusOK, body: `{}`},
+	}, nil)
+
+	transport := testTransport(nil)
+	transport.Timeout = 50 * time.Millisecond
+
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("the response body could not be read: %v", err)
+	}
+
+	if string(body) != `{}` {
+		t.Errorf("body = %q, want %q", body, `{}`)
+	}
+	if got := atomic.LoadInt32(calls); got != 2 {
+		t.Errorf("server received %d requests, want 2", got)
+	}
+}
+
+func TestRetryTransportCancelled(t *testing.T) {
+	server, calls := scriptedServer(t, []scriptedResponse{
+		{status: http.StatusTooManyRequests, header: http.Header{"Retry-After": {"0.5"}}},
+	}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, err = (&http.Client{Transport: testTransport(nil)}).Do(req)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Errorf("the request returned after %v rather than when it was cancelled", elapsed)
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("server received %d requests, want 1", got)
+	}
+}
+
+func TestClientOnRetry(t *testing.T) {
+	server, _ := scriptedServer(t, []scriptedResponse{
+		{status: http.StatusTooManyRequests, body: `{"message": "You are being rate limited.", "retry_after": 0.001}`},
+		{status: http.StatusOK, body: `{"id": "1", "username": "alice"}`},
+	}, nil)
+
+	var events []RetryEvent
+	client := NewClient("token", OnRetry(func(event RetryEvent) {
+		events = append(events, event)
+	}))
+	client.BaseURL = server.URL
+
+	user, err := client.CurrentUser(context.Background())
+	if err != nil {
+		t.Fatalf("CurrentUser failed: %v", err)
+	}
+	if user.Username != "alice" {
+		t.Errorf("Username = %q, want %q", user.Username, "alice")
+	}
+	if len(events) != 1 {
+		t.Errorf("OnRetry called %d times, want 1", len(events))
+	}
+}
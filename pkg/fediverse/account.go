@@ -0,0 +1,54 @@
+package fediverse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type Profile struct {
+	ID             string   `json:"id"`
+	Username       string   `json:"username"`
+	Acct           string   `json:"acct"`
+	DisplayName    string   `json:"display_name"`
+	URL            string   `json:"url"`
+	Avatar         string   `json:"avatar"`
+	FollowersCount int      `json:"followers_count"`
+	Moved          *Profile `json:"moved,omitempty"`
+}
+
+func (c *Client) LookupAccount(ctx context.Context, host, handle string) (*Profile, error) {
+	username, _, _ := strings.Cut(handle, "@")
+	query := url.Values{"acct": {username}}
+	endpoint := "https://" + host + "/api/v1/accounts/lookup?" + query.Encode()
+
+	var profile Profile
+	if err := c.getJSON(ctx, endpoint, "application/json", &profile); err != nil {
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusGone) {
+			return nil, fmt.Errorf("%w: %s has no account named %s", ErrAccountNotFound, host, username)
+		}
+		return nil, fmt.Errorf("the account lookup for %s failed: %w", handle, err)
+	}
+	if profile.ID == "" {
+		return nil, fmt.Errorf("the account lookup for %s returned an empty account", handle)
+	}
+	return &profile, nil
+}
+
+func (p *Profile) Handle(domain string) string {
+	if strings.Contains(p.Acct, "@") {
+		return p.Acct
+	}
+	return p.Acct + "@" + domain
+}
+
+func (p *Profile) Name() string {
+	if p.DisplayName != "" {
+		return p.DisplayName
+	}
+	return p.Username
+}
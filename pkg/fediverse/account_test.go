@@ -0,0 +1,149 @@
+package fediverse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestLookupAccount(t *testing.T) {
+	tests := []struct {
+		name    string
+		route   testRoute
+		want    string
+		moved   string
+		wantErr error
+	}{
+		{
+			name:  "found",
+			route: testRoute{body: `{"id": "109", "username": "alice", "acct": "alice", "display_name": "Alice"}`},
+			want:  "alice@example.social",
+		},
+		{
+			name:  "moved",
+			route: testRoute{body: `{"id": "109", "username": "alice", "acct": "alice", "moved": {"id": "7", "username": "alice", "acct": "alice@new.example"}}`},
+			want:  "alice@example.social",
+			moved: "alice@new.example",
+		},
+		{
+			name:    "not found",
+			route:   testRoute{status: http.StatusNotFound, body: `{"error": "Record not found"}`},
+			wantErr: ErrAccountNotFound,
+		},
+		{
+			name:    "gone",
+			route:   testRoute{status: http.StatusGone},
+			wantErr: ErrAccountNotFound,
+		},
+		{
+			name:    "server error",
+			route:   testRoute{status: http.StatusInternalServerError},
+			wantErr: ErrInstanceUnreachable,
+		},
+		{
+			name:  "empty id",
+			route: testRoute{body: `{"id": "", "username": "alice", "acct": "alice"}`},
+		},
+		{
+			name:  "empty object",
+			route: testRoute{body: `{}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, map[string]testRoute{"/api/v1/accounts/lookup": tt.route})
+			got, err := ts.client.LookupAccount(context.Background(), ts.host, "alice@example.social")
+			if len(ts.requests) != 1 {
+				t.Fatalf("the server received %d requests, want 1", len(ts.requests))
+			}
+			if acct := ts.requests[0].URL.Query().Get("acct"); acct != "alice" {
+				t.Errorf("acct = %q, want %q", acct, "alice")
+			}
+
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("LookupAccount() = %+v, want an error", got)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("LookupAccount() error = %v, want %v", err, tt.wantErr)
+				}
+				if tt.wantErr == nil && errors.Is(err, ErrAccountNotFound) {
+					t.Errorf("LookupAccount() error = %v, should not be ErrAccountNotFound", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LookupAccount failed: %v", err)
+			}
+			if handle := got.Handle("example.social"); handle != tt.want {
+				t.Errorf("Handle() = %q, want %q", handle, tt.want)
+			}
+			if got.Moved == nil {
+				if tt.moved != "" {
+					t.Errorf("Moved = nil, want %q", tt.moved)
+				}
+				return
+			}
+			if moved := got.Moved.Handle("example.social"); moved != tt.moved {
+				t.Errorf("Moved.Handle() = %q, want %q", moved, tt.moved)
+			}
+		})
+	}
+}
+
+func TestProfileHandle(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile Profile
+		want    string
+		moved   string
+	}{
+		{
+			name:    "local account",
+			profile: Profile{Username: "alice", Acct: "alice"},
+			want:    "alice@example.social",
+		},
+		{
+			name:    "remote account",
+			profile: Profile{Username: "bob", Acct: "bob@other.example"},
+			want:    "bob@other.example",
+		},
+		{
+			name:    "moved to another instance",
+			profile: Profile{Username: "alice", Acct: "alice", Moved: &Profile{Username: "alice", Acct: "alice@new.example"}},
+			want:    "alice@example.social",
+			moved:   "alice@new.example",
+		},
+		{
+			name:    "moved within the instance",
+			profile: Profile{Username: "alice", Acct: "alice", Moved: &Profile{Username: "alice2", Acct: "alice2"}},
+			want:    "alice@example.social",
+			moved:   "alice2@example.social",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.profile.Handle("example.social"); got != tt.want {
+				t.Errorf("Handle() = %q, want %q", got, tt.want)
+			}
+			if tt.profile.Moved == nil {
+				return
+			}
+			if got := tt.profile.Moved.Handle("example.social"); got != tt.moved {
+				t.Errorf("Moved.Handle() = %q, want %q", got, tt.moved)
+			}
+		})
+	}
+}
+
+func TestProfileName(t *testing.T) {
+	if got := (&Profile{Username: "alice", DisplayName: "Alice"}).Name(); got != "Alice" {
+		t.Errorf("Name() = %q, want %q", got, "Alice")
+	}
+	if got := (&Profile{Username: "alice"}).Name(); got != "alice" {
+		t.Errorf("Name() without a display name = %q, want %q", got, "alice")
+	}
+}
@@ -0,0 +1,49 @@
+package fediverse
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const activityPubAccept = `application/activity+json, application/ld+json; profile="https://www.w3.org/ns/activitystreams"`
+
+type Actor struct {
+	ID                string `json:"id"`
+	Type              string `json:"type"`
+	PreferredUsername string `json:"preferredUsername"`
+	URL               string `json:"url"`
+}
+
+func (c *Client) FetchActor(ctx context.Context, actorURL string) (*Actor, error) {
+	var actor Actor
+	if err := c.getJSON(ctx, actorURL, activityPubAccept, &actor); err != nil {
+		return nil, fmt.Errorf("the ActivityPub actor at %s could not be retrieved: %w", actorURL, err)
+	}
+	if actor.ID == "" || actor.PreferredUsername == "" {
+		return nil, fmt.Errorf("%s did not return an ActivityPub actor", actorURL)
+	}
+	return &actor, nil
+}
+
+func (c *Client) ResolveProfileURL(ctx context.Context, profileURL string) (Handle, error) {
+	parsed, err := parseProfileURL(strings.TrimSpace(profileURL))
+	if err != nil {
+		return Handle{}, err
+	}
+	profile, _ := url.Parse(strings.TrimSpace(profileURL))
+	if host, _ := normaliseHost(profile.Host); parsed.Domain != host {
+		return parsed, nil
+	}
+
+	actor, err := c.FetchActor(ctx, profile.String())
+	if err != nil {
+		return parsed, err
+	}
+	id, err := url.Parse(actor.ID)
+	if err != nil || id.Host == "" {
+		return parsed, fmt.Errorf("the ActivityPub actor at %s has an invalid id: %s", profileURL, actor.ID)
+	}
+	return newHandle(actor.PreferredUsername, id.Host)
+}
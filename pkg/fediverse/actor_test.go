@@ -0,0 +1,111 @@
+package fediverse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestResolveProfileURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		actor   *testRoute
+		want    string
+		fails   bool
+		wantErr error
+	}{
+		{
+			name:  "actor on the same host",
+			path:  "/@alice",
+			actor: &testRoute{body: `{"id": "https://{host}/users/alice", "type": "Person", "preferredUsername": "alice"}`},
+			want:  "alice@{host}",
+		},
+		{
+			name:  "preferred username differs from the path",
+			path:  "/users/Alice",
+			actor: &testRoute{body: `{"id": "https://{host}/users/Alice", "type": "Person", "preferredUsername": "alice_real"}`},
+			want:  "alice_real@{host}",
+		},
+		{
+			name:  "actor id on another host",
+			path:  "/@alice",
+			actor: &testRoute{body: `{"id": "https://Social.Example.net/users/alice", "type": "Person", "preferredUsername": "alice"}`},
+			want:  "alice@social.example.net",
+		},
+		{
+			name: "remote account",
+			path: "/@bob@other.example",
+			want: "bob@other.example",
+		},
+		{
+			name:  "actor not found",
+			path:  "/@alice",
+			actor: &testRoute{status: http.StatusNotFound},
+			want:  "alice@{host}",
+			fails: true,
+		},
+		{
+			name:  "actor without a preferred username",
+			path:  "/@alice",
+			actor: &testRoute{body: `{"id": "https://{host}/users/alice", "type": "Person"}`},
+			want:  "alice@{host}",
+			fails: true,
+		},
+		{
+			name:  "actor with a relative id",
+			path:  "/@alice",
+			actor: &testRoute{body: `{"id": "/users/alice", "type": "Person", "preferredUsername": "alice"}`},
+			want:  "alice@{host}",
+			fails: true,
+		},
+		{
+			name:    "actor with an invalid username",
+			path:    "/@alice",
+			actor:   &testRoute{body: `{"id": "https://{host}/users/alice", "type": "Person", "preferredUsername": "al ice"}`},
+			fails:   true,
+			wantErr: ErrInvalidHandle,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes := map[string]testRoute{}
+			if tt.actor != nil {
+				routes[tt.path] = *tt.actor
+			}
+			ts := newTestServer(t, routes)
+
+			got, err := ts.client.ResolveProfileURL(context.Background(), "https://"+ts.host+tt.path)
+			if paths := ts.paths(); tt.actor == nil && len(paths) != 0 {
+				t.Errorf("requested %v for a remote account", paths)
+			} else if tt.actor != nil {
+				if len(paths) != 1 || paths[0] != tt.path {
+					t.Fatalf("requested %v, want [%s]", paths, tt.path)
+				}
+				if accept := ts.requests[0].Header.Get("Accept"); accept != activityPubAccept {
+					t.Errorf("Accept = %q, want %q", accept, activityPubAccept)
+				}
+			}
+
+			if tt.fails {
+				if err == nil {
+					t.Errorf("ResolveProfileURL() = %q, want an error", got.String())
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("ResolveProfileURL() error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("ResolveProfileURL failed: %v", err)
+			}
+			if tt.want == "" {
+				return
+			}
+			if want := strings.ReplaceAll(tt.want, "{host}", ts.host); got.String() != want {
+				t.Errorf("ResolveProfileURL() = %q, want %q", got.String(), want)
+			}
+		})
+	}
+}
@@ -1,30 +1,48 @@
 package fediverse
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"regexp"
 	"strings"
 	"time"
 )
 
-var handleRegex = regexp.MustCompile(`^[a-zA-Z0-9_]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
+const (
+	DefaultUserAgent = "fediscord"
+	DefaultTimeout   = 10 * time.Second
+)
 
 var incompatiblePlatforms = []string{"misskey", "firefish", "calckey", "foundkey"}
 
-type InstanceInfo struct {
-	Version string `json:"version"`
+var (
+	ErrInvalidHandle        = errors.New("the supplied Fediverse handle does not conform to the expected format; the required format is: username@instance.domain")
+	ErrInstanceUnreachable  = errors.New("the specified instance could not be reached")
+	ErrInstanceIncompatible = errors.New("the instance is not compatible with the Mastodon API")
+	ErrAccountNotFound      = errors.New("the account does not exist on the instance")
+)
+
+type Client struct {
+	UserAgent  string
+	HTTPClient *http.Client
+}
+
+func NewClient() *Client {
+	return &Client{
+		UserAgent:  DefaultUserAgent,
+		HTTPClient: &http.Client{Timeout: DefaultTimeout},
+	}
 }
 
 func ValidateHandle(handle string) (string, error) {
-	handle = strings.TrimPrefix(handle, "@")
-	if !handleRegex.MatchString(handle) {
-		return "", errors.New("the supplied Fediverse handle does not conform to the expected format; the required format is: username@instance.domain")
+	parsed, err := ParseHandle(handle)
+	if err != nil {
+		return "", err
 	}
-	return handle, nil
+	return parsed.String(), nil
 }
 
 func ExtractInstance(handle string) string {
@@ -35,32 +53,99 @@ func ExtractInstance(handle string) string {
 	return ""
 }
 
-func CheckMastodonAPISupport(instance string) (string, error) {
-	client := &http.Client{Timeout: 10 * time.Second}
-	apiURL := fmt.Sprintf("https://%s/api/v1/instance", instance)
+func (c *Client) CheckMastodonAPISupport(ctx context.Context, instance string) (string, error) {
+	software, err := c.NodeInfo(ctx, instance)
+	if errors.Is(err, context.Canceled) {
+		return "", err
+	}
+	if err == nil {
+		if platform, ok := software.Platform(); ok {
+			if !platform.Compatible {
+				return software.String(), fmt.Errorf("%w: the instance is operating on %s, which does not implement the Mastodon API", ErrInstanceIncompatible, software)
+			}
+			return software.String(), nil
+		}
+	}
 
-	resp, err := client.Get(apiURL)
-	if err != nil {
-		return "", fmt.Errorf("the specified instance could not be reached: %w", err)
+	version, err := c.checkInstanceAPI(ctx, instance)
+	if err != nil || software == nil {
+		return version, err
 	}
-	defer resp.Body.Close()
+	return software.String() + " (Mastodon API " + version + ")", nil
+}
 
-	body, err := io.ReadAll(resp.Body)
+func (c *Client) checkInstanceAPI(ctx context.Context, instance string) (string, error) {
+	info, err := c.Instance(ctx, instance)
 	if err != nil {
-		return "", fmt.Errorf("the instance response could not be read: %w", err)
+		if errors.Is(err, ErrInstanceUnreachable) || errors.Is(err, context.Canceled) {
+			return "", err
+		}
+		return "", fmt.Errorf("%w: the instance did not return a valid Mastodon API response", ErrInstanceIncompatible)
 	}
-
-	var info InstanceInfo
-	if err := json.Unmarshal(body, &info); err != nil || info.Version == "" {
-		return "", errors.New("the instance did not return a valid Mastodon API v1 response; it may not be compatible with this tool")
+	if info.Version == "" {
+		return "", fmt.Errorf("%w: the instance did not return a valid Mastodon API response", ErrInstanceIncompatible)
 	}
 
 	versionLower := strings.ToLower(info.Version)
 	for _, platform := range incompatiblePlatforms {
 		if strings.Contains(versionLower, platform) {
-			return info.Version, fmt.Errorf("the instance is operating on %s, which does not implement the Mastodon API and is therefore incompatible with this tool", info.Version)
+			return info.Version, fmt.Errorf("%w: the instance is operating on %s, which does not implement the Mastodon API", ErrInstanceIncompatible, info.Version)
 		}
 	}
 
 	return info.Version, nil
 }
+
+func (c *Client) getJSON(ctx context.Context, endpoint, accept string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("the HTTP request could not be constructed: %w", err)
+	}
+	req.Header.Set("Accept", accept)
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInstanceUnreachable, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%w: the instance response could not be read: %w", ErrInstanceUnreachable, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{URL: endpoint, StatusCode: resp.StatusCode}
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("the response from %s could not be parsed: %w", endpoint, err)
+	}
+	return nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned HTTP %d", e.URL, e.StatusCode)
+}
+
+func (e *StatusError) Unwrap() error {
+	if e.StatusCode >= http.StatusInternalServerError {
+		return ErrInstanceUnreachable
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package fediverse
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type testRoute struct {
+	status int
+	body   string
+}
+
+type testServer struct {
+	client   *Client
+	host     string
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func newTestServer(t *testing.T, routes map[string]testRoute) *testServer {
+	t.Helper()
+	ts := &testServer{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ts.mu.Lock()
+		ts.requests = append(ts.requests, r)
+		ts.mu.Unlock()
+
+		route, ok := routes[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if route.status == 0 {
+			route.status = http.StatusOK
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(route.status)
+		io.WriteString(w, strings.ReplaceAll(route.body, "{host}", ts.host))
+	}))
+	t.Cleanup(server.Close)
+
+	ts.host = strings.TrimPrefix(server.URL, "https://")
+	ts.client = NewClient()
+	ts.client.HTTPClient = server.Client()
+	return ts
+}
+
+func (ts *testServer) paths() []string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	var paths []string
+	for _, r := range ts.requests {
+		paths = append(paths, r.URL.Path)
+	}
+	return paths
+}
+
+func nodeInfoRoutes(software string) map[string]testRoute {
+	return map[string]testRoute{
+		"/.well-known/nodeinfo": {body: `{"links": [{"rel": "http://nodeinfo.diaspora.software/ns/schema/2.0", "href": "https://{host}/nodeinfo/2.0"}]}`},
+		"/nodeinfo/2.0":         {body: `{"version": "2.0", "software": ` + software + `}`},
+	}
+}
+
+func withRoutes(routes map[string]testRoute, extra map[string]testRoute) map[string]testRoute {
+	for path, route := range extra {
+		routes[path] = route
+	}
+	return routes
+}
+
+func TestCheckMastodonAPISupport(t *testing.T) {
+	tests := []struct {
+		name    string
+		routes  map[string]testRoute
+		want    string
+		wantErr error
+		paths   []string
+	}{
+		{
+			name:   "mastodon",
+			routes: nodeInfoRoutes(`{"name": "mastodon", "version": "4.2.8"}`),
+			want:   "Mastodon 4.2.8",
+			paths:  []string{"/.well-known/nodeinfo", "/nodeinfo/2.0"},
+		},
+		{
+			name:   "glitch",
+			routes: nodeInfoRoutes(`{"name": "mastodon", "version": "4.2.8+glitch"}`),
+			want:   "Glitch 4.2.8+glitch",
+			paths:  []string{"/.well-known/nodeinfo", "/nodeinfo/2.0"},
+		},
+		{
+			name:   "hometown",
+			routes: nodeInfoRoutes(`{"name": "mastodon", "version": "4.0.2+hometown-1.1.1"}`),
+			want:   "Hometown 4.0.2+hometown-1.1.1",
+			paths:  []string{"/.well-known/nodeinfo", "/nodeinfo/2.0"},
+		},
+		{
+			name:    "misskey",
+			routes:  nodeInfoRoutes(`{"name": "misskey", "version": "2024.5.0"}`),
+			want:    "Misskey 2024.5.0",
+			wantErr: ErrInstanceIncompatible,
+			paths:   []string{"/.well-known/nodeinfo", "/nodeinfo/2.0"},
+		},
+		{
+			name: "unknown software with the Mastodon API",
+			routes: withRoutes(nodeInfoRoutes(`{"name": "wildebeest", "version": "0.1.0"}`), map[string]testRoute{
+				"/api/v2/instance": {body: `{"domain": "{host}", "version": "4.0.2 (compatible; Wildebeest 0.1.0)"}`},
+			}),
+			want:  "wildebeest 0.1.0 (Mastodon API 4.0.2 (compatible; Wildebeest 0.1.0))",
+			paths: []string{"/.well-known/nodeinfo", "/nodeinfo/2.0", "/api/v2/instance"},
+		},
+		{
+			name: "unknown software without the Mastodon API",
+			routes: withRoutes(nodeInfoRoutes(`{"name": "wildebeest", "version": "0.1.0"}`), map[string]testRoute{
+				"/api/v1/instance": {body: `{"uri": "{host}"}`},
+			}),
+			wantErr: ErrInstanceIncompatible,
+			paths:   []string{"/.well-known/nodeinfo", "/nodeinfo/2.0", "/api/v2/instance", "/api/v1/instance"},
+		},
+		{
+			name: "fallback to the v1 instance endpoint",
+			routes: map[string]testRoute{
+				"/api/v1/instance": {body: `{"uri": "{host}", "version": "3.5.3"}`},
+			},
+			want:  "3.5.3",
+			paths: []string{"/.well-known/nodeinfo", "/api/v2/instance", "/api/v1/instance"},
+		},
+		{
+			name: "fallback finding an incompatible platform",
+			routes: map[string]testRoute{
+				"/api/v1/instance": {body: `{"uri": "{host}", "version": "3.0.0 (compatible; Misskey 12.0.0)"}`},
+			},
+			want:    "3.0.0 (compatible; Misskey 12.0.0)",
+			wantErr: ErrInstanceIncompatible,
+			paths:   []string{"/.well-known/nodeinfo", "/api/v2/instance", "/api/v1/instance"},
+		},
+		{
+			name:    "no API",
+			routes:  map[string]testRoute{},
+			wantErr: ErrInstanceIncompatible,
+			paths:   []string{"/.well-known/nodeinfo", "/api/v2/instance", "/api/v1/instance"},
+		},
+		{
+			name: "server error",
+			routes: map[string]testRoute{
+				"/.well-known/nodeinfo": {status: http.StatusBadGateway},
+				"/api/v2/instance":      {status: http.StatusBadGateway},
+				"/api/v1/instance":      {status: http.StatusBadGateway},
+			},
+			wantErr: ErrInstanceUnreachable,
+			paths:   []string{"/.well-known/nodeinfo", "/api/v2/instance", "/api/v1/instance"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, tt.routes)
+			got, err := ts.client.CheckMastodonAPISupport(context.Background(), ts.host)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("CheckMastodonAPISupport() error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Errorf("CheckMastodonAPISupport failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("CheckMastodonAPISupport() = %q, want %q", got, tt.want)
+			}
+			if paths := ts.paths(); !reflect.DeepEqual(paths, tt.paths) {
+				t.Errorf("requested %v, want %v", paths, tt.paths)
+			}
+		})
+	}
+}
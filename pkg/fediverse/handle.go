@@ -0,0 +1,155 @@
+package fediverse
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+)
+
+type Handle struct {
+	User    string
+	Domain  string
+	APIHost string
+}
+
+var hostProfile = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.Transitional(false),
+)
+
+func ParseHandle(input string) (Handle, error) {
+	input = strings.TrimSpace(input)
+
+	switch {
+	case IsProfileURL(input):
+		return parseProfileURL(input)
+	case strings.HasPrefix(strings.ToLower(input), "acct:"):
+		input = input[len("acct:"):]
+	}
+
+	input = strings.TrimPrefix(input, "@")
+	user, host, ok := strings.Cut(input, "@")
+	if !ok {
+		return Handle{}, ErrInvalidHandle
+	}
+	return newHandle(user, host)
+}
+
+func IsProfileURL(input string) bool {
+	input = strings.ToLower(strings.TrimSpace(input))
+	return strings.HasPrefix(input, "https://") || strings.HasPrefix(input, "http://")
+}
+
+func parseProfileURL(input string) (Handle, error) {
+	profile, err := url.Parse(input)
+	if err != nil || profile.Host == "" {
+		return Handle{}, fmt.Errorf("%w: %q is not a valid profile URL", ErrInvalidHandle, input)
+	}
+
+	var user string
+	segments := strings.Split(strings.Trim(profile.Path, "/"), "/")
+	switch {
+	case len(segments) == 1 && strings.HasPrefix(segments[0], "@"):
+		user = segments[0][1:]
+	case len(segments) == 2 && segments[0] == "users":
+		user = segments[1]
+	default:
+		return Handle{}, fmt.Errorf("%w: %q is not a recognised profile URL", ErrInvalidHandle, input)
+	}
+
+	user, remote, isRemote := strings.Cut(user, "@")
+	if isRemote {
+		return newHandle(user, remote)
+	}
+	return newHandle(user, profile.Host)
+}
+
+func newHandle(user, host string) (Handle, error) {
+	if !validUser(user) {
+		return Handle{}, fmt.Errorf("%w: %q is not a valid username", ErrInvalidHandle, user)
+	}
+	domain, err := normaliseHost(host)
+	if err != nil {
+		return Handle{}, err
+	}
+	return Handle{User: user, Domain: domain, APIHost: domain}, nil
+}
+
+func validUser(user string) bool {
+	if user == "" || strings.HasPrefix(user, ".") || strings.HasSuffix(user, ".") {
+		return false
+	}
+	for _, r := range user {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+func normaliseHost(host string) (string, error) {
+	name, port := host, ""
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		name, port = host[:i], host[i+1:]
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return "", fmt.Errorf("%w: %q is not a valid port", ErrInvalidHandle, port)
+		}
+	}
+
+	ascii, err := hostProfile.ToASCII(strings.TrimSuffix(name, "."))
+	if err != nil || ascii == "" {
+		return "", fmt.Errorf("%w: %q is not a valid domain name", ErrInvalidHandle, name)
+	}
+	if net.ParseIP(ascii) == nil {
+		if !strings.Contains(ascii, ".") && ascii != "localhost" {
+			return "", fmt.Errorf("%w: %q is not a fully qualified domain name", ErrInvalidHandle, name)
+		}
+		for _, label := range strings.Split(ascii, ".") {
+			if !validLabel(label) {
+				return "", fmt.Errorf("%w: %q is not a valid domain name", ErrInvalidHandle, name)
+			}
+		}
+	}
+
+	if port != "" {
+		return net.JoinHostPort(ascii, port), nil
+	}
+	return ascii, nil
+}
+
+func validLabel(label string) bool {
+	if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+		return false
+	}
+	for _, r := range label {
+		if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+func (h Handle) String() string {
+	return h.User + "@" + h.Domain
+}
+
+func (h Handle) Display() string {
+	name, port, err := net.SplitHostPort(h.Domain)
+	if err != nil {
+		name, port = h.Domain, ""
+	}
+	unicodeName, err := hostProfile.ToUnicode(name)
+	if err != nil {
+		unicodeName = name
+	}
+	if port != "" {
+		unicodeName = net.JoinHostPort(unicodeName, port)
+	}
+	return h.User + "@" + unicodeName
+}
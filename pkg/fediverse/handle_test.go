@@ -0,0 +1,108 @@
+package fediverse
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseHandle(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Handle
+		display string
+	}{
+		{"plain", "alice@example.social", Handle{"alice", "example.social", "example.social"}, "alice@example.social"},
+		{"leading at", "@alice@example.social", Handle{"alice", "example.social", "example.social"}, "alice@example.social"},
+		{"surrounding space", "  alice@example.social\n", Handle{"alice", "example.social", "example.social"}, "alice@example.social"},
+		{"upper-case domain", "alice@Example.Social", Handle{"alice", "example.social", "example.social"}, "alice@example.social"},
+		{"trailing dot", "alice@example.social.", Handle{"alice", "example.social", "example.social"}, "alice@example.social"},
+		{"interior dots", "a.l-i_ce@example.social", Handle{"a.l-i_ce", "example.social", "example.social"}, "a.l-i_ce@example.social"},
+		{"acct uri", "acct:alice@example.social", Handle{"alice", "example.social", "example.social"}, "alice@example.social"},
+		{"acct uri upper case", "ACCT:alice@example.social", Handle{"alice", "example.social", "example.social"}, "alice@example.social"},
+		{"idn", "alice@bücher.example", Handle{"alice", "xn--bcher-kva.example", "xn--bcher-kva.example"}, "alice@bücher.example"},
+		{"punycode", "alice@xn--bcher-kva.example", Handle{"alice", "xn--bcher-kva.example", "xn--bcher-kva.example"}, "alice@bücher.example"},
+		{"port", "alice@example.social:8443", Handle{"alice", "example.social:8443", "example.social:8443"}, "alice@example.social:8443"},
+		{"idn with port", "alice@bücher.example:8443", Handle{"alice", "xn--bcher-kva.example:8443", "xn--bcher-kva.example:8443"}, "alice@bücher.example:8443"},
+		{"localhost", "alice@localhost:3000", Handle{"alice", "localhost:3000", "localhost:3000"}, "alice@localhost:3000"},
+		{"ip address", "alice@192.0.2.1", Handle{"alice", "192.0.2.1", "192.0.2.1"}, "alice@192.0.2.1"},
+		{"mastodon url", "https://example.social/@alice", Handle{"alice", "example.social", "example.social"}, "alice@example.social"},
+		{"mastodon url with slash", "https://example.social/@alice/", Handle{"alice", "example.social", "example.social"}, "alice@example.social"},
+		{"users url", "https://example.social/users/alice", Handle{"alice", "example.social", "example.social"}, "alice@example.social"},
+		{"http url", "http://example.social/@alice", Handle{"alice", "example.social", "example.social"}, "alice@example.social"},
+		{"url with port", "https://example.social:8443/@alice", Handle{"alice", "example.social:8443", "example.social:8443"}, "alice@example.social:8443"},
+		{"remote account url", "https://example.social/@bob@other.example", Handle{"bob", "other.example", "other.example"}, "bob@other.example"},
+		{"idn url", "https://bücher.example/@alice", Handle{"alice", "xn--bcher-kva.example", "xn--bcher-kva.example"}, "alice@bücher.example"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHandle(tt.input)
+			if err != nil {
+				t.Fatalf("ParseHandle(%q) returned error: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseHandle(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+			if display := got.Display(); display != tt.display {
+				t.Errorf("Display() = %q, want %q", display, tt.display)
+			}
+		})
+	}
+}
+
+func TestParseHandleInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"empty", ""},
+		{"no domain", "alice"},
+		{"empty user", "@example.social"},
+		{"empty domain", "alice@"},
+		{"leading dot in user", ".alice@example.social"},
+		{"trailing dot in user", "alice.@example.social"},
+		{"space in user", "al ice@example.social"},
+		{"single label", "alice@example"},
+		{"underscore in domain", "alice@exa_mple.social"},
+		{"label with leading hyphen", "alice@-example.social"},
+		{"empty label", "alice@example..social"},
+		{"port zero", "alice@example.social:0"},
+		{"port too large", "alice@example.social:65536"},
+		{"port not numeric", "alice@example.social:https"},
+		{"empty port", "alice@example.social:"},
+		{"url without user", "https://example.social/"},
+		{"url with other path", "https://example.social/about"},
+		{"url with status path", "https://example.social/@alice/123456"},
+		{"url without host", "https:///@alice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHandle(tt.input)
+			if !errors.Is(err, ErrInvalidHandle) {
+				t.Errorf("ParseHandle(%q) = %+v, %v; want ErrInvalidHandle", tt.input, got, err)
+			}
+		})
+	}
+}
+
+func TestIsProfileURL(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"https://example.social/@alice", true},
+		{"HTTP://example.social/@alice", true},
+		{" https://example.social/users/alice", true},
+		{"alice@example.social", false},
+		{"acct:alice@example.social", false},
+		{"example.social/@alice", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsProfileURL(tt.input); got != tt.want {
+			t.Errorf("IsProfileURL(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package fediverse
+
+import (
+	"context"
+	"errors"
+)
+
+type InstanceInfo struct {
+	APIVersion    int           `json:"api_version"`
+	Domain        string        `json:"domain"`
+	Title         string        `json:"title"`
+	Version       string        `json:"version"`
+	SourceURL     string        `json:"source_url,omitempty"`
+	Description   string        `json:"description,omitempty"`
+	Registrations Registrations `json:"registrations"`
+	Contact       Contact       `json:"contact"`
+	Configuration Configuration `json:"configuration"`
+}
+
+type Registrations struct {
+	Enabled          bool   `json:"enabled"`
+	ApprovalRequired bool   `json:"approval_required"`
+	Message          string `json:"message,omitempty"`
+}
+
+type Contact struct {
+	Email   string          `json:"email,omitempty"`
+	Account *ContactAccount `json:"account,omitempty"`
+}
+
+type ContactAccount struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	Acct        string `json:"acct"`
+	DisplayName string `json:"display_name"`
+	URL         string `json:"url"`
+}
+
+type Configuration struct {
+	Statuses struct {
+		MaxCharacters       int `json:"max_characters"`
+		MaxMediaAttachments int `json:"max_media_attachments"`
+	} `json:"statuses"`
+	MediaAttachments struct {
+		ImageSizeLimit int64 `json:"image_size_limit"`
+		VideoSizeLimit int64 `json:"video_size_limit"`
+	} `json:"media_attachments"`
+	Polls struct {
+		MaxOptions             int `json:"max_options"`
+		MaxCharactersPerOption int `json:"max_characters_per_option"`
+	} `json:"polls"`
+	Accounts struct {
+		MaxFeaturedTags int `json:"max_featured_tags"`
+	} `json:"accounts"`
+}
+
+type instanceV2 struct {
+	Domain        string        `json:"domain"`
+	Title         string        `json:"title"`
+	Version       string        `json:"version"`
+	SourceURL     string        `json:"source_url"`
+	Description   string        `json:"description"`
+	Registrations Registrations `json:"registrations"`
+	Contact       Contact       `json:"contact"`
+	Configuration Configuration `json:"configuration"`
+}
+
+type instanceV1 struct {
+	URI              string          `json:"uri"`
+	Title            string          `json:"title"`
+	Version          string          `json:"version"`
+	Description      string          `json:"short_description"`
+	Email            string          `json:"email"`
+	Registrations    bool            `json:"registrations"`
+	ApprovalRequired bool            `json:"approval_required"`
+	ContactAccount   *ContactAccount `json:"contact_account"`
+	Configuration    Configuration   `json:"configuration"`
+}
+
+func (c *Client) Instance(ctx context.Context, instance string) (*InstanceInfo, error) {
+	var v2 instanceV2
+	err := c.getJSON(ctx, "https://"+instance+"/api/v2/instance", "application/json", &v2)
+	if err == nil && v2.Version != "" {
+		return &InstanceInfo{
+			APIVersion:    2,
+			Domain:        v2.Domain,
+			Title:         v2.Title,
+			Version:       v2.Version,
+			SourceURL:     v2.SourceURL,
+			Description:   v2.Description,
+			Registrations: v2.Registrations,
+			Contact:       v2.Contact,
+			Configuration: v2.Configuration,
+		}, nil
+	}
+
+	var statusErr *StatusError
+	if err != nil && !errors.As(err, &statusErr) && (errors.Is(err, ErrInstanceUnreachable) || errors.Is(err, context.Canceled)) {
+		return nil, err
+	}
+
+	var v1 instanceV1
+	if err := c.getJSON(ctx, "https://"+instance+"/api/v1/instance", "application/json", &v1); err != nil {
+		return nil, err
+	}
+	domain := v1.URI
+	if domain == "" {
+		domain = instance
+	}
+	return &InstanceInfo{
+		APIVersion:  1,
+		Domain:      domain,
+		Title:       v1.Title,
+		Version:     v1.Version,
+		Description: v1.Description,
+		Registrations: Registrations{
+			Enabled:          v1.Registrations,
+			ApprovalRequired: v1.ApprovalRequired,
+		},
+		Contact:       Contact{Email: v1.Email, Account: v1.ContactAccount},
+		Configuration: v1.Configuration,
+	}, nil
+}
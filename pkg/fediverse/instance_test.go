@@ -0,0 +1,154 @@
+package fediverse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestInstance(t *testing.T) {
+	v1 := testRoute{body: `{
+		"uri": "{host}",
+		"title": "Example Social",
+		"version": "3.5.3",
+		"short_description": "A small instance",
+		"email": "admin@example.social",
+		"registrations": true,
+		"approval_required": true,
+		"contact_account": {"id": "1", "username": "admin", "acct": "admin", "display_name": "Admin", "url": "https://{host}/@admin"},
+		"configuration": {"statuses": {"max_characters": 500, "max_media_attachments": 4}}
+	}`}
+	v1Info := func(host string) *InstanceInfo {
+		info := &InstanceInfo{
+			APIVersion:    1,
+			Domain:        host,
+			Title:         "Example Social",
+			Version:       "3.5.3",
+			Description:   "A small instance",
+			Registrations: Registrations{Enabled: true, ApprovalRequired: true},
+			Contact: Contact{
+				Email:   "admin@example.social",
+				Account: &ContactAccount{ID: "1", Username: "admin", Acct: "admin", DisplayName: "Admin", URL: "https://" + host + "/@admin"},
+			},
+		}
+		info.Configuration.Statuses.MaxCharacters = 500
+		info.Configuration.Statuses.MaxMediaAttachments = 4
+		return info
+	}
+
+	tests := []struct {
+		name    string
+		routes  map[string]testRoute
+		want    func(host string) *InstanceInfo
+		wantErr error
+		paths   []string
+	}{
+		{
+			name: "v2",
+			routes: map[string]testRoute{
+				"/api/v2/instance": {body: `{
+					"domain": "{host}",
+					"title": "Example Social",
+					"version": "4.2.8",
+					"source_url": "https://github.com/mastodon/mastodon",
+					"description": "A small instance",
+					"registrations": {"enabled": true, "approval_required": false, "message": "Welcome"},
+					"contact": {"email": "admin@example.social"}
+				}`},
+				"/api/v1/instance": v1,
+			},
+			want: func(host string) *InstanceInfo {
+				return &InstanceInfo{
+					APIVersion:    2,
+					Domain:        host,
+					Title:         "Example Social",
+					Version:       "4.2.8",
+					SourceURL:     "https://github.com/mastodon/mastodon",
+					Description:   "A small instance",
+					Registrations: Registrations{Enabled: true, Message: "Welcome"},
+					Contact:       Contact{Email: "admin@example.social"},
+				}
+			},
+			paths: []string{"/api/v2/instance"},
+		},
+		{
+			name:   "v2 not found",
+			routes: map[string]testRoute{"/api/v1/instance": v1},
+			want:   v1Info,
+			paths:  []string{"/api/v2/instance", "/api/v1/instance"},
+		},
+		{
+			name: "v2 server error",
+			routes: map[string]testRoute{
+				"/api/v2/instance": {status: http.StatusServiceUnavailable},
+				"/api/v1/instance": v1,
+			},
+			want:  v1Info,
+			paths: []string{"/api/v2/instance", "/api/v1/instance"},
+		},
+		{
+			name: "v2 without a version",
+			routes: map[string]testRoute{
+				"/api/v2/instance": {body: `{"domain": "{host}", "title": "Example Social"}`},
+				"/api/v1/instance": v1,
+			},
+			want:  v1Info,
+			paths: []string{"/api/v2/instance", "/api/v1/instance"},
+		},
+		{
+			name: "v1 without a uri",
+			routes: map[string]testRoute{
+				"/api/v1/instance": {body: `{"title": "Example Social", "version": "2.9.0"}`},
+			},
+			want: func(host string) *InstanceInfo {
+				return &InstanceInfo{APIVersion: 1, Domain: host, Title: "Example Social", Version: "2.9.0"}
+			},
+			paths: []string{"/api/v2/instance", "/api/v1/instance"},
+		},
+		{
+			name: "both server errors",
+			routes: map[string]testRoute{
+				"/api/v2/instance": {status: http.StatusBadGateway},
+				"/api/v1/instance": {status: http.StatusBadGateway},
+			},
+			wantErr: ErrInstanceUnreachable,
+			paths:   []string{"/api/v2/instance", "/api/v1/instance"},
+		},
+		{
+			name:   "neither endpoint",
+			routes: map[string]testRoute{},
+			paths:  []string{"/api/v2/instance", "/api/v1/instance"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, tt.routes)
+			got, err := ts.client.Instance(context.Background(), ts.host)
+			if paths := ts.paths(); !reflect.DeepEqual(paths, tt.paths) {
+				t.Errorf("requested %v, want %v", paths, tt.paths)
+			}
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("Instance() = %+v, want an error", got)
+				}
+				var statusErr *StatusError
+				if !errors.As(err, &statusErr) {
+					t.Errorf("Instance() error = %v, want a StatusError", err)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("Instance() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Instance failed: %v", err)
+			}
+			if want := tt.want(ts.host); !reflect.DeepEqual(got, want) {
+				t.Errorf("Instance() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package fediverse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const nodeInfoSchemaPrefix = "http://nodeinfo.diaspora.software/ns/schema/2."
+
+type Software struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type Platform struct {
+	Name       string
+	Compatible bool
+}
+
+var platformTable = map[string]Platform{
+	"mastodon":      {"Mastodon", true},
+	"glitch-soc":    {"Glitch", true},
+	"glitchsoc":     {"Glitch", true},
+	"hometown":      {"Hometown", true},
+	"fedibird":      {"Fedibird", true},
+	"kmyblue":       {"kmyblue", true},
+	"akkoma":        {"Akkoma", true},
+	"pleroma":       {"Pleroma", true},
+	"gotosocial":    {"GoToSocial", true},
+	"misskey":       {"Misskey", false},
+	"firefish":      {"Firefish", false},
+	"calckey":       {"Calckey", false},
+	"foundkey":      {"Foundkey", false},
+	"sharkey":       {"Sharkey", false},
+	"iceshrimp":     {"Iceshrimp", false},
+	"iceshrimp.net": {"Iceshrimp.NET", false},
+	"cherrypick":    {"CherryPick", false},
+	"catodon":       {"Catodon", false},
+	"meisskey":      {"Meisskey", false},
+}
+
+var versionVariants = map[string]string{
+	"+glitch":   "glitch-soc",
+	"+hometown": "hometown",
+}
+
+type nodeInfoIndex struct {
+	Links []struct {
+		Rel  string `json:"rel"`
+		Href string `json:"href"`
+	} `json:"links"`
+}
+
+type nodeInfoDocument struct {
+	Software Software `json:"software"`
+}
+
+func (c *Client) NodeInfo(ctx context.Context, instance string) (*Software, error) {
+	var index nodeInfoIndex
+	if err := c.getJSON(ctx, "https://"+instance+"/.well-known/nodeinfo", "application/json", &index); err != nil {
+		return nil, fmt.Errorf("the NodeInfo index could not be retrieved: %w", err)
+	}
+
+	var schemas []string
+	hrefs := make(map[string]string)
+	for _, link := range index.Links {
+		if strings.HasPrefix(link.Rel, nodeInfoSchemaPrefix) {
+			schemas = append(schemas, link.Rel)
+			hrefs[link.Rel] = link.Href
+		}
+	}
+	if len(schemas) == 0 {
+		return nil, errors.New("the instance does not publish a NodeInfo 2.x document")
+	}
+	sort.Strings(schemas)
+
+	var doc nodeInfoDocument
+	if err := c.getJSON(ctx, hrefs[schemas[len(schemas)-1]], "application/json", &doc); err != nil {
+		return nil, fmt.Errorf("the NodeInfo document could not be retrieved: %w", err)
+	}
+	if doc.Software.Name == "" {
+		return nil, errors.New("the NodeInfo document does not identify the server software")
+	}
+	return &doc.Software, nil
+}
+
+func (s *Software) Platform() (Platform, bool) {
+	name := strings.ToLower(s.Name)
+	if name == "mastodon" {
+		version := strings.ToLower(s.Version)
+		for suffix, variant := range versionVariants {
+			if strings.Contains(version, suffix) {
+				name = variant
+			}
+		}
+	}
+
+	platform, ok := platformTable[name]
+	return platform, ok
+}
+
+func (s *Software) String() string {
+	name := s.Name
+	if platform, ok := s.Platform(); ok {
+		name = platform.Name
+	}
+	if s.Version == "" {
+		return name
+	}
+	return name + " " + s.Version
+}
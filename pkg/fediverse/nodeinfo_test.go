@@ -0,0 +1,129 @@
+package fediverse
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestSoftwarePlatform(t *testing.T) {
+	tests := []struct {
+		software   Software
+		platform   string
+		compatible bool
+		known      bool
+		display    string
+	}{
+		{Software{"mastodon", "4.2.8"}, "Mastodon", true, true, "Mastodon 4.2.8"},
+		{Software{"Mastodon", "4.2.8+glitch"}, "Glitch", true, true, "Glitch 4.2.8+glitch"},
+		{Software{"mastodon", "4.1.0+hometown-1.1.1"}, "Hometown", true, true, "Hometown 4.1.0+hometown-1.1.1"},
+		{Software{"glitch-soc", "4.2.8"}, "Glitch", true, true, "Glitch 4.2.8"},
+		{Software{"hometown", "1.1.1"}, "Hometown", true, true, "Hometown 1.1.1"},
+		{Software{"akkoma", "3.10.4"}, "Akkoma", true, true, "Akkoma 3.10.4"},
+		{Software{"pleroma", "2.6.0"}, "Pleroma", true, true, "Pleroma 2.6.0"},
+		{Software{"gotosocial", "0.15.0"}, "GoToSocial", true, true, "GoToSocial 0.15.0"},
+		{Software{"misskey", "2024.5.0"}, "Misskey", false, true, "Misskey 2024.5.0"},
+		{Software{"sharkey", "2024.3.1"}, "Sharkey", false, true, "Sharkey 2024.3.1"},
+		{Software{"iceshrimp.net", "2024.1"}, "Iceshrimp.NET", false, true, "Iceshrimp.NET 2024.1"},
+		{Software{"misskey", "2024.5.0+glitch"}, "Misskey", false, true, "Misskey 2024.5.0+glitch"},
+		{Software{"wildebeest", "0.1.0"}, "", false, false, "wildebeest 0.1.0"},
+		{Software{"mastodon", ""}, "Mastodon", true, true, "Mastodon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.display, func(t *testing.T) {
+			platform, ok := tt.software.Platform()
+			if ok != tt.known {
+				t.Fatalf("Platform() known = %v, want %v", ok, tt.known)
+			}
+			if platform.Name != tt.platform || platform.Compatible != tt.compatible {
+				t.Errorf("Platform() = %+v, want {%s %v}", platform, tt.platform, tt.compatible)
+			}
+			if got := tt.software.String(); got != tt.display {
+				t.Errorf("String() = %q, want %q", got, tt.display)
+			}
+		})
+	}
+}
+
+func TestNodeInfo(t *testing.T) {
+	document := testRoute{body: `{"version": "2.1", "software": {"name": "mastodon", "version": "4.2.8"}}`}
+	stale := testRoute{body: `{"version": "2.0", "software": {"name": "stale", "version": "1.0"}}`}
+	tests := []struct {
+		name   string
+		routes map[string]testRoute
+		want   *Software
+	}{
+		{
+			name: "single schema",
+			routes: map[string]testRoute{
+				"/.well-known/nodeinfo": {body: `{"links": [{"rel": "http://nodeinfo.diaspora.software/ns/schema/2.0", "href": "https://{host}/nodeinfo/2.0"}]}`},
+				"/nodeinfo/2.0":         document,
+			},
+			want: &Software{"mastodon", "4.2.8"},
+		},
+		{
+			name: "highest 2.x schema",
+			routes: map[string]testRoute{
+				"/.well-known/nodeinfo": {body: `{"links": [
+					{"rel": "http://nodeinfo.diaspora.software/ns/schema/2.1", "href": "https://{host}/nodeinfo/2.1"},
+					{"rel": "http://nodeinfo.diaspora.software/ns/schema/1.0", "href": "https://{host}/nodeinfo/1.0"},
+					{"rel": "http://nodeinfo.diaspora.software/ns/schema/2.0", "href": "https://{host}/nodeinfo/2.0"}
+				]}`},
+				"/nodeinfo/1.0": stale,
+				"/nodeinfo/2.0": stale,
+				"/nodeinfo/2.1": document,
+			},
+			want: &Software{"mastodon", "4.2.8"},
+		},
+		{
+			name: "only 1.x schemas",
+			routes: map[string]testRoute{
+				"/.well-known/nodeinfo": {body: `{"links": [{"rel": "http://nodeinfo.diaspora.software/ns/schema/1.1", "href": "https://{host}/nodeinfo/1.1"}]}`},
+				"/nodeinfo/1.1":         stale,
+			},
+		},
+		{
+			name: "document without software",
+			routes: map[string]testRoute{
+				"/.well-known/nodeinfo": {body: `{"links": [{"rel": "http://nodeinfo.diaspora.software/ns/schema/2.0", "href": "https://{host}/nodeinfo/2.0"}]}`},
+				"/nodeinfo/2.0":         {body: `{"version": "2.0", "software": {}}`},
+			},
+		},
+		{
+			name: "missing document",
+			routes: map[string]testRoute{
+				"/.well-known/nodeinfo": {body: `{"links": [{"rel": "http://nodeinfo.diaspora.software/ns/schema/2.0", "href": "https://{host}/nodeinfo/2.0"}]}`},
+			},
+		},
+		{
+			name:   "missing index",
+			routes: map[string]testRoute{},
+		},
+		{
+			name: "malformed index",
+			routes: map[string]testRoute{
+				"/.well-known/nodeinfo": {status: http.StatusOK, body: `<html></html>`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, tt.routes)
+			got, err := ts.client.NodeInfo(context.Background(), ts.host)
+			if tt.want == nil {
+				if err == nil {
+					t.Errorf("NodeInfo() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NodeInfo failed: %v", err)
+			}
+			if *got != *tt.want {
+				t.Errorf("NodeInfo() = %+v, want %+v", *got, *tt.want)
+			}
+		})
+	}
+}
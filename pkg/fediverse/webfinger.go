@@ -0,0 +1,79 @@
+package fediverse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	relSelf        = "self"
+	relProfilePage = "http://webfinger.net/rel/profile-page"
+)
+
+type Account struct {
+	Handle     string
+	Host       string
+	ActorURL   string
+	ProfileURL string
+}
+
+type webFingerResponse struct {
+	Subject string          `json:"subject"`
+	Aliases []string        `json:"aliases"`
+	Links   []webFingerLink `json:"links"`
+}
+
+type webFingerLink struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type"`
+	Href string `json:"href"`
+}
+
+func (c *Client) Resolve(ctx context.Context, parsed Handle) (*Account, error) {
+	handle := parsed.String()
+	query := url.Values{"resource": {"acct:" + handle}}
+	endpoint := "https://" + parsed.Domain + "/.well-known/webfinger?" + query.Encode()
+
+	var doc webFingerResponse
+	if err := c.getJSON(ctx, endpoint, "application/jrd+json, application/json", &doc); err != nil {
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%w: WebFinger has no record of %s", ErrAccountNotFound, handle)
+		}
+		return nil, fmt.Errorf("the WebFinger lookup for %s failed: %w", handle, err)
+	}
+
+	account := &Account{Handle: handle, Host: parsed.APIHost}
+	if subject := strings.TrimPrefix(doc.Subject, "acct:"); subject != doc.Subject && ExtractInstance(subject) != "" {
+		account.Handle = subject
+	}
+
+	for _, link := range doc.Links {
+		switch {
+		case link.Rel == relSelf && isActivityPubType(link.Type):
+			account.ActorURL = link.Href
+		case link.Rel == relProfilePage && account.ProfileURL == "":
+			account.ProfileURL = link.Href
+		}
+	}
+
+	if account.ActorURL == "" {
+		return nil, fmt.Errorf("the WebFinger record for %s does not contain an ActivityPub self link", handle)
+	}
+	actor, err := url.Parse(account.ActorURL)
+	if err != nil || actor.Host == "" {
+		return nil, fmt.Errorf("the WebFinger record for %s contains an invalid self link: %s", handle, account.ActorURL)
+	}
+	account.Host = actor.Host
+
+	return account, nil
+}
+
+func isActivityPubType(mediaType string) bool {
+	return mediaType == "application/activity+json" ||
+		strings.HasPrefix(mediaType, "application/ld+json")
+}
@@ -0,0 +1,141 @@
+package fediverse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		route   testRoute
+		want    Account
+		wantErr error
+	}{
+		{
+			name: "local account",
+			route: testRoute{body: `{
+				"subject": "acct:alice@{host}",
+				"links": [
+					{"rel": "http://webfinger.net/rel/profile-page", "type": "text/html", "href": "https://{host}/@alice"},
+					{"rel": "self", "type": "application/activity+json", "href": "https://{host}/users/alice"}
+				]
+			}`},
+			want: Account{Handle: "alice@{host}", Host: "{host}", ActorURL: "https://{host}/users/alice", ProfileURL: "https://{host}/@alice"},
+		},
+		{
+			name: "subject on the account domain",
+			route: testRoute{body: `{
+				"subject": "acct:alice@example.social",
+				"links": [{"rel": "self", "type": "application/activity+json", "href": "https://{host}/users/alice"}]
+			}`},
+			want: Account{Handle: "alice@example.social", Host: "{host}", ActorURL: "https://{host}/users/alice"},
+		},
+		{
+			name: "subject that is not an acct uri",
+			route: testRoute{body: `{
+				"subject": "https://{host}/users/alice",
+				"links": [{"rel": "self", "type": "application/ld+json; profile=\"https://www.w3.org/ns/activitystreams\"", "href": "https://{host}/users/alice"}]
+			}`},
+			want: Account{Handle: "alice@{host}", Host: "{host}", ActorURL: "https://{host}/users/alice"},
+		},
+		{
+			name: "self link on another host",
+			route: testRoute{body: `{
+				"subject": "acct:alice@{host}",
+				"links": [{"rel": "self", "type": "application/activity+json", "href": "https://social.example.net/users/alice"}]
+			}`},
+			want: Account{Handle: "alice@{host}", Host: "social.example.net", ActorURL: "https://social.example.net/users/alice"},
+		},
+		{
+			name: "first profile page kept",
+			route: testRoute{body: `{
+				"links": [
+					{"rel": "http://webfinger.net/rel/profile-page", "href": "https://{host}/@alice"},
+					{"rel": "http://webfinger.net/rel/profile-page", "href": "https://{host}/users/alice"},
+					{"rel": "self", "type": "application/activity+json", "href": "https://{host}/users/alice"}
+				]
+			}`},
+			want: Account{Handle: "alice@{host}", Host: "{host}", ActorURL: "https://{host}/users/alice", ProfileURL: "https://{host}/@alice"},
+		},
+		{
+			name: "missing self link",
+			route: testRoute{body: `{
+				"subject": "acct:alice@{host}",
+				"links": [{"rel": "http://webfinger.net/rel/profile-page", "type": "text/html", "href": "https://{host}/@alice"}]
+			}`},
+		},
+		{
+			name: "self link that is not ActivityPub",
+			route: testRoute{body: `{
+				"links": [{"rel": "self", "type": "text/html", "href": "https://{host}/@alice"}]
+			}`},
+		},
+		{
+			name: "self link without a host",
+			route: testRoute{body: `{
+				"links": [{"rel": "self", "type": "application/activity+json", "href": "/users/alice"}]
+			}`},
+		},
+		{
+			name:    "unknown account",
+			route:   testRoute{status: http.StatusNotFound},
+			wantErr: ErrAccountNotFound,
+		},
+		{
+			name:    "server error",
+			route:   testRoute{status: http.StatusBadGateway},
+			wantErr: ErrInstanceUnreachable,
+		},
+		{
+			name:  "malformed response",
+			route: testRoute{body: `<html></html>`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, map[string]testRoute{"/.well-known/webfinger": tt.route})
+			handle, err := ParseHandle("alice@" + ts.host)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ts.client.Resolve(context.Background(), handle)
+			if len(ts.requests) != 1 {
+				t.Fatalf("the server received %d requests, want 1", len(ts.requests))
+			}
+			if resource := ts.requests[0].URL.Query().Get("resource"); resource != "acct:alice@"+ts.host {
+				t.Errorf("resource = %q, want %q", resource, "acct:alice@"+ts.host)
+			}
+
+			if tt.want.Handle == "" {
+				if err == nil {
+					t.Fatalf("Resolve() = %+v, want an error", got)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("Resolve() error = %v, want %v", err, tt.wantErr)
+				}
+				if tt.wantErr == nil && errors.Is(err, ErrAccountNotFound) {
+					t.Errorf("Resolve() error = %v, should not be ErrAccountNotFound", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve failed: %v", err)
+			}
+			want := Account{
+				Handle:     strings.ReplaceAll(tt.want.Handle, "{host}", ts.host),
+				Host:       strings.ReplaceAll(tt.want.Host, "{host}", ts.host),
+				ActorURL:   strings.ReplaceAll(tt.want.ActorURL, "{host}", ts.host),
+				ProfileURL: strings.ReplaceAll(tt.want.ProfileURL, "{host}", ts.host),
+			}
+			if *got != want {
+				t.Errorf("Resolve() = %+v, want %+v", *got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jimed-rand/fediscord/pkg/config"
+)
+
+func Record(paths *config.Paths, event string) error {
+	cfg, err := paths.ReadConfig()
+	if err != nil || !cfg.Preferences.RecordHistory {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(paths.HistoryFile), 0700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(paths.HistoryFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "%s [%s] %s\n", time.Now().UTC().Format(time.RFC3339), paths.Profile, event)
+	return err
+}
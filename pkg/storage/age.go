@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+var (
+	ErrDecryption   = errors.New("the token could not be decrypted")
+	ErrNoPassphrase = errors.New("no passphrase is available")
+
+	errWrongPassphrase = errors.New("the passphrase is incorrect")
+)
+
+type PassphraseSource func(confirm bool) (string, error)
+
+var passphraseSource PassphraseSource
+
+var scryptWorkFactor = 18
+
+func SetPassphraseSource(source PassphraseSource) {
+	passphraseSource = source
+}
+
+func passphrase(confirm bool) (string, error) {
+	if passphraseSource == nil {
+		return "", ErrNoPassphrase
+	}
+	value, err := passphraseSource(confirm)
+	if err != nil {
+		return "", err
+	}
+	if value == "" {
+		return "", fmt.Errorf("%w: the passphrase is empty", ErrNoPassphrase)
+	}
+	return value, nil
+}
+
+func encryptAge(plaintext, passphrase string) ([]byte, error) {
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	recipient.SetWorkFactor(scryptWorkFactor)
+
+	var buf bytes.Buffer
+	armored := armor.NewWriter(&buf)
+	w, err := age.Encrypt(armored, recipient)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(w, plaintext); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	if err := armored.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decryptAge(ciphertext []byte, passphrase string) (string, error) {
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return "", err
+	}
+
+	r, err := age.Decrypt(armor.NewReader(bytes.NewReader(ciphertext)), identity)
+	if err != nil {
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			return "", errWrongPassphrase
+		}
+		return "", err
+	}
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(plaintext)), nil
+}
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jimed-rand/fediscord/pkg/config"
+	"github.com/jimed-rand/fediscord/pkg/history"
+)
+
+const (
+	BundleFormat  = "fediscord-bundle"
+	BundleVersion = 1
+)
+
+var (
+	ErrBundle          = errors.New("the backup bundle could not be opened")
+	ErrExistingData    = errors.New("the profile already holds a Fediverse handle or a Discord token")
+	ErrNothingToExport = fmt.Errorf("the profile holds no handle, storage method, or token to export: %w", ErrNotFound)
+)
+
+type Bundle struct {
+	Created    time.Time `json:"created"`
+	Profile    string    `json:"profile"`
+	Handle     string    `json:"handle,omitempty"`
+	Encryption string    `json:"encryption,omitempty"`
+	Token      string    `json:"token,omitempty"`
+}
+
+type bundleEnvelope struct {
+	Format  string          `json:"format"`
+	Version int             `json:"version"`
+	Digest  string          `json:"digest"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func ExportBundle(paths *config.Paths, passphrase string) ([]byte, *Bundle, error) {
+	if passphrase == "" {
+		return nil, nil, fmt.Errorf("%w: the passphrase is empty", ErrNoPassphrase)
+	}
+	bundle := &Bundle{Created: time.Now().UTC().Truncate(time.Second), Profile: paths.Profile}
+
+	var err error
+	if bundle.Handle, err = RetrieveHandle(paths); err != nil && !errors.Is(err, ErrHandleNotFound) {
+		return nil, nil, err
+	}
+	if bundle.Encryption, err = BackendPreference(paths); err != nil && !errors.Is(err, ErrEncryptionNotSet) {
+		return nil, nil, err
+	}
+	if bundle.Token, err = RetrieveToken(paths); err != nil && !errors.Is(err, ErrTokenNotFound) {
+		return nil, nil, err
+	}
+	if bundle.Handle == "" && bundle.Encryption == "" && bundle.Token == "" {
+		return nil, nil, ErrNothingToExport
+	}
+
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, nil, err
+	}
+	digest := sha256.Sum256(data)
+	envelope, err := json.Marshal(bundleEnvelope{
+		Format:  BundleFormat,
+		Version: BundleVersion,
+		Digest:  "sha256:" + hex.EncodeToString(digest[:]),
+		Data:    data,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encrypted, err := encryptAge(string(envelope), passphrase)
+	if err != nil {
+		return nil, nil, fmt.Errorf("the bundle could not be encrypted: %w", err)
+	}
+	history.Record(paths, "configuration exported to a backup bundle")
+	return encrypted, bundle, nil
+}
+
+func OpenBundle(data []byte, passphrase string) (*Bundle, error) {
+	plaintext, err := decryptAge(data, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBundle, err)
+	}
+
+	var envelope bundleEnvelope
+	if err := json.Unmarshal([]byte(plaintext), &envelope); err != nil {
+		return nil, fmt.Errorf("%w: the contents are malformed: %v", ErrBundle, err)
+	}
+	if envelope.Format != BundleFormat {
+		return nil, fmt.Errorf("%w: the file is not a fediscord bundle", ErrBundle)
+	}
+	if envelope.Version > BundleVersion {
+		return nil, fmt.Errorf("%w: the bundle has version %d, while version %d is supported", ErrBundle, envelope.Version, BundleVersion)
+	}
+	digest := sha256.Sum256(envelope.Data)
+	if envelope.Digest != "sha256:"+hex.EncodeToString(digest[:]) {
+		return nil, fmt.Errorf("%w: the integrity check failed", ErrBundle)
+	}
+
+	bundle := &Bundle{}
+	if err := json.Unmarshal(envelope.Data, bundle); err != nil {
+		return nil, fmt.Errorf("%w: the contents are malformed: %v", ErrBundle, err)
+	}
+	if bundle.Encryption != "" {
+		if _, err := Backend(&config.Paths{}, bundle.Encryption); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBundle, err)
+		}
+	}
+	return bundle, nil
+}
+
+func HasData(paths *config.Paths) bool {
+	if _, err := RetrieveHandle(paths); err == nil {
+		return true
+	}
+	_, err := LocateToken(paths)
+	return err == nil
+}
+
+func ImportBundle(paths *config.Paths, bundle *Bundle) error {
+	unlock, err := paths.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if bundle.Encryption != "" {
+		if err := SetBackendPreference(paths, bundle.Encryption); err != nil {
+			return err
+		}
+	}
+	if bundle.Token != "" {
+		if err := StoreToken(paths, bundle.Token); err != nil {
+			return err
+		}
+	} else if err := deleteOthers(paths, nil); err != nil {
+		return err
+	}
+	if bundle.Handle != "" {
+		if err := StoreHandle(paths, bundle.Handle); err != nil {
+			return err
+		}
+	} else if err := updateProfile(paths, func(profile *config.Profile) {
+		profile.Handle = ""
+	}); err != nil {
+		return err
+	}
+	history.Record(paths, "configuration imported from a backup bundle of profile "+bundle.Profile)
+	return nil
+}
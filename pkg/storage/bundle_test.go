@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jimed-rand/fediscord/pkg/config"
+)
+
+const bundlePassphrase = "correct horse battery staple"
+
+func sealBundle(t *testing.T, envelope any) []byte {
+	t.Helper()
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := encryptAge(string(data), bundlePassphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encrypted
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	source := newTestPaths(t)
+	if err := SetBackendPreference(source, config.EncryptionPlain); err != nil {
+		t.Fatal(err)
+	}
+	if err := StoreToken(source, "discord-token"); err != nil {
+		t.Fatal(err)
+	}
+	if err := StoreHandle(source, "alice@example.social"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, exported, err := ExportBundle(source, bundlePassphrase)
+	if err != nil {
+		t.Fatalf("ExportBundle failed: %v", err)
+	}
+	want := Bundle{
+		Created:    exported.Created,
+		Profile:    config.DefaultProfile,
+		Handle:     "alice@example.social",
+		Encryption: config.EncryptionPlain,
+		Token:      "discord-token",
+	}
+	if *exported != want {
+		t.Errorf("exported bundle = %+v, want %+v", *exported, want)
+	}
+	if time.Since(exported.Created) > time.Minute {
+		t.Errorf("Created = %v", exported.Created)
+	}
+
+	opened, err := OpenBundle(data, bundlePassphrase)
+	if err != nil {
+		t.Fatalf("OpenBundle failed: %v", err)
+	}
+	if *opened != want {
+		t.Errorf("opened bundle = %+v, want %+v", *opened, want)
+	}
+
+	if err := source.CreateProfile("restored"); err != nil {
+		t.Fatal(err)
+	}
+	target := source.ForProfile("restored")
+	if err := ImportBundle(target, opened); err != nil {
+		t.Fatalf("ImportBundle failed: %v", err)
+	}
+	if token, err := RetrieveToken(target); err != nil || token != want.Token {
+		t.Errorf("imported token = %q, %v; want %q", token, err, want.Token)
+	}
+	if handle, err := RetrieveHandle(target); err != nil || handle != want.Handle {
+		t.Errorf("imported handle = %q, %v; want %q", handle, err, want.Handle)
+	}
+	if name, err := BackendPreference(target); err != nil || name != want.Encryption {
+		t.Errorf("imported storage method = %q, %v; want %q", name, err, want.Encryption)
+	}
+
+	replacement := *opened
+	replacement.Token = "replacement-token"
+	if err := ImportBundle(target, &replacement); err != nil {
+		t.Fatalf("ImportBundle into a profile holding data failed: %v", err)
+	}
+	if token, err := RetrieveToken(target); err != nil || token != replacement.Token {
+		t.Errorf("replaced token = %q, %v; want %q", token, err, replacement.Token)
+	}
+
+	handleOnly := *opened
+	handleOnly.Token = ""
+	handleOnly.Handle = "bob@example.social"
+	if err := ImportBundle(target, &handleOnly); err != nil {
+		t.Fatalf("ImportBundle of a bundle without a token failed: %v", err)
+	}
+	if token, err := RetrieveToken(target); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("token after importing a bundle without one = %q, %v; want ErrTokenNotFound", token, err)
+	}
+	if handle, err := RetrieveHandle(target); err != nil || handle != handleOnly.Handle {
+		t.Errorf("replaced handle = %q, %v; want %q", handle, err, handleOnly.Handle)
+	}
+
+	tokenOnly := *opened
+	tokenOnly.Handle = ""
+	if err := ImportBundle(target, &tokenOnly); err != nil {
+		t.Fatalf("ImportBundle of a bundle without a handle failed: %v", err)
+	}
+	if handle, err := RetrieveHandle(target); !errors.Is(err, ErrHandleNotFound) {
+		t.Errorf("handle after importing a bundle without one = %q, %v; want ErrHandleNotFound", handle, err)
+	}
+	if !HasData(target) {
+		t.Errorf("HasData = false after importing a token")
+	}
+}
+
+func TestExportBundleEmptyProfile(t *testing.T) {
+	paths := newTestPaths(t)
+	if _, _, err := ExportBundle(paths, bundlePassphrase); !errors.Is(err, ErrNothingToExport) {
+		t.Errorf("ExportBundle of an empty profile = %v, want ErrNothingToExport", err)
+	}
+	if _, _, err := ExportBundle(paths, ""); !errors.Is(err, ErrNoPassphrase) {
+		t.Errorf("ExportBundle without a passphrase = %v, want ErrNoPassphrase", err)
+	}
+}
+
+func TestOpenBundleRejectsTampering(t *testing.T) {
+	data := json.RawMessage(`{"created":"2026-01-02T03:04:05Z","profile":"default","handle":"alice@example.social","encryption":"plain","token":"discord-token"}`)
+	digest := "sha256:" + sha256Hex(data)
+	valid := sealBundle(t, bundleEnvelope{Format: BundleFormat, Version: BundleVersion, Digest: digest, Data: data})
+
+	if _, err := OpenBundle(valid, bundlePassphrase); err != nil {
+		t.Fatalf("OpenBundle of the untampered bundle failed: %v", err)
+	}
+
+	corrupted := append([]byte(nil), valid...)
+	corrupted[len(corrupted)/2] ^= 0x01
+
+	tests := []struct {
+		name       string
+		data       []byte
+		passphrase string
+	}{
+		{
+			name:       "wrong passphrase",
+			data:       valid,
+			passphrase: "wrong passphrase",
+		},
+		{
+			name: "corrupted ciphertext",
+			data: corrupted,
+		},
+		{
+			name: "not encrypted",
+			data: []byte(`{"format":"fediscord-bundle"}`),
+		},
+		{
+			name: "data altered after the digest",
+			data: sealBundle(t, bundleEnvelope{Format: BundleFormat, Version: BundleVersion, Digest: digest,
+				Data: json.RawMessage(`{"created":"2026-01-02T03:04:05Z","profile":"default","handle":"mallory@example.social","encryption":"plain","token":"discord-token"}`)}),
+		},
+		{
+			name: "digest missing",
+			data: sealBundle(t, bundleEnvelope{Format: BundleFormat, Version: BundleVersion, Data: data}),
+		},
+		{
+			name: "other format",
+			data: sealBundle(t, bundleEnvelope{Format: "other", Version: BundleVersion, Digest: digest, Data: data}),
+		},
+		{
+			name: "newer version",
+			data: sealBundle(t, bundleEnvelope{Format: BundleFormat, Version: BundleVersion + 1, Digest: digest, Data: data}),
+		},
+		{
+			name: "unknown storage method",
+			data: func() []byte {
+				unknown := json.RawMessage(`{"created":"2026-01-02T03:04:05Z","profile":"default","encryption":"rot13"}`)
+				return sealBundle(t, bundleEnvelope{Format: BundleFormat, Version: BundleVersion, Digest: "sha256:" + sha256Hex(unknown), Data: unknown})
+			}(),
+		},
+		{
+			name: "malformed envelope",
+			data: func() []byte {
+				encrypted, err := encryptAge("not json", bundlePassphrase)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return encrypted
+			}(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			passphrase := tt.passphrase
+			if passphrase == "" {
+				passphrase = bundlePassphrase
+			}
+			bundle, err := OpenBundle(tt.data, passphrase)
+			if !errors.Is(err, ErrBundle) {
+				t.Errorf("OpenBundle = %+v, %v; want ErrBundle", bundle, err)
+			}
+		})
+	}
+}
+
+func sha256Hex(data []byte) string {
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:])
+}
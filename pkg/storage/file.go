@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/jimed-rand/fediscord/pkg/config"
+)
+
+const previousSuffix = ".previous"
+
+type plainFile struct {
+	paths *config.Paths
+}
+
+func (f *plainFile) Name() string        { return config.EncryptionPlain }
+func (f *plainFile) Description() string { return "Plain text" }
+func (f *plainFile) Encrypted() bool     { return false }
+func (f *plainFile) Available() error    { return nil }
+func (f *plainFile) Present() bool       { return fileExists(f.paths.TokenPlain) }
+func (f *plainFile) Location() string    { return f.paths.TokenPlain }
+func (f *plainFile) Delete() error       { return config.WipeFile(f.paths.TokenPlain) }
+
+func (f *plainFile) Store(token string) error {
+	return replaceTokenFile(f.paths.TokenPlain, []byte(token))
+}
+
+func (f *plainFile) Retrieve() (string, error) {
+	data, err := os.ReadFile(f.paths.TokenPlain)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+type ageFile struct {
+	paths *config.Paths
+}
+
+func (f *ageFile) Name() string        { return config.EncryptionAge }
+func (f *ageFile) Description() string { return "Encrypted file" }
+func (f *ageFile) Encrypted() bool     { return true }
+func (f *ageFile) Available() error    { return nil }
+func (f *ageFile) Present() bool       { return fileExists(f.paths.TokenAge) }
+func (f *ageFile) Location() string    { return f.paths.TokenAge }
+func (f *ageFile) Delete() error       { return config.WipeFile(f.paths.TokenAge) }
+
+func (f *ageFile) Store(token string) error {
+	pass, err := passphrase(true)
+	if err != nil {
+		return err
+	}
+	data, err := encryptAge(token, pass)
+	if err != nil {
+		return fmt.Errorf("the token could not be encrypted: %w", err)
+	}
+	return replaceTokenFile(f.paths.TokenAge, data)
+}
+
+func (f *ageFile) Retrieve() (string, error) {
+	data, err := os.ReadFile(f.paths.TokenAge)
+	if err != nil {
+		return "", err
+	}
+	pass, err := passphrase(false)
+	if err != nil {
+		return "", err
+	}
+	token, err := decryptAge(data, pass)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrDecryption, err)
+	}
+	return token, nil
+}
+
+type gpgFile struct {
+	paths *config.Paths
+}
+
+func (f *gpgFile) Name() string        { return config.EncryptionGPG }
+func (f *gpgFile) Description() string { return "GPG-encrypted file" }
+func (f *gpgFile) Encrypted() bool     { return true }
+func (f *gpgFile) Present() bool       { return fileExists(f.paths.TokenGPG) }
+func (f *gpgFile) Location() string    { return f.paths.TokenGPG }
+func (f *gpgFile) Delete() error       { return config.WipeFile(f.paths.TokenGPG) }
+
+func (f *gpgFile) Available() error {
+	if !IsGPGAvailable() {
+		return ErrGPGUnavailable
+	}
+	return nil
+}
+
+func (f *gpgFile) Store(token string) error {
+	out, err := runTool(token, "gpg", "--symmetric", "--cipher-algo", "AES256", "--output", "-")
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrGPG, err)
+	}
+	return replaceTokenFile(f.paths.TokenGPG, []byte(out))
+}
+
+func (f *gpgFile) Retrieve() (string, error) {
+	if !IsGPGAvailable() {
+		return "", fmt.Errorf("%w, however a GPG-encrypted token was detected; please install GPG to proceed", ErrGPGUnavailable)
+	}
+	out, err := runTool("", "gpg", "--decrypt", "--quiet", f.paths.TokenGPG)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrGPG, err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func IsGPGAvailable() bool {
+	_, err := exec.LookPath("gpg")
+	return err == nil
+}
+
+func GPGVersion() (string, error) {
+	out, err := runTool("", "gpg", "--version")
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrGPG, err)
+	}
+	version, _, _ := strings.Cut(out, "\n")
+	return strings.TrimSpace(version), nil
+}
+
+func CheckGPGAgent() error {
+	if _, err := runTool("", "gpg-connect-agent", "/bye"); err != nil {
+		lines := strings.Split(strings.TrimSpace(err.Error()), "\n")
+		return fmt.Errorf("%w: the GPG agent could not be reached: %s", ErrGPG, lines[len(lines)-1])
+	}
+	return nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return !os.IsNotExist(err)
+}
+
+func replaceTokenFile(path string, data []byte) error {
+	previous := path + previousSuffix
+	if err := config.WipeFile(previous); err != nil {
+		return err
+	}
+	linked := os.Link(path, previous) == nil
+	if err := config.WriteFileAtomic(path, data, 0600); err != nil {
+		if linked {
+			os.Remove(previous)
+		}
+		return err
+	}
+	if linked {
+		return config.WipeFile(previous)
+	}
+	return nil
+}
+
+func isTokenFile(backend SecretStore) bool {
+	switch backend.(type) {
+	case *plainFile, *ageFile, *gpgFile:
+		return true
+	}
+	return false
+}
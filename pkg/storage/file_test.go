@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jimed-rand/fediscord/pkg/config"
+)
+
+func TestMain(m *testing.M) {
+	scryptWorkFactor = 10
+	os.Exit(m.Run())
+}
+
+func newTestPaths(t *testing.T) *config.Paths {
+	t.Helper()
+	t.Setenv(config.ConfigDirEnv, filepath.Join(t.TempDir(), "fediscord"))
+	t.Setenv(passStoreDirEnv, t.TempDir())
+	t.Setenv(sessionBusAddressEnv, "unix:path="+filepath.Join(t.TempDir(), "bus"))
+
+	paths, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := paths.Initialise(); err != nil {
+		t.Fatal(err)
+	}
+	return paths
+}
+
+func usePassphrase(t *testing.T, value string) {
+	t.Helper()
+	previous := passphraseSource
+	SetPassphraseSource(func(bool) (string, error) { return value, nil })
+	t.Cleanup(func() { SetPassphraseSource(previous) })
+}
+
+func TestReplaceTokenFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		previous string
+	}{
+		{name: "new file"},
+		{name: "existing file", existing: "old-token"},
+		{name: "stale previous copy", existing: "old-token", previous: "older-token"},
+		{name: "stale previous copy only", previous: "older-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "discord_token.txt")
+			observer := filepath.Join(dir, "observer")
+
+			if tt.existing != "" {
+				if err := os.WriteFile(path, []byte(tt.existing), 0600); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Link(path, observer); err != nil {
+					t.Skipf("hard links are not supported: %v", err)
+				}
+			}
+			if tt.previous != "" {
+				if err := os.WriteFile(path+previousSuffix, []byte(tt.previous), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := replaceTokenFile(path, []byte("new-token")); err != nil {
+				t.Fatalf("replaceTokenFile failed: %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "new-token" {
+				t.Errorf("contents = %q, want %q", data, "new-token")
+			}
+			if fileExists(path + previousSuffix) {
+				t.Errorf("the previous copy was left behind")
+			}
+			if temporary := config.TemporaryFiles(path); len(temporary) != 0 {
+				t.Errorf("temporary files were left behind: %v", temporary)
+			}
+			if tt.existing != "" {
+				old, err := os.ReadFile(observer)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(old) != 0 {
+					t.Errorf("the replaced token survived: %q", old)
+				}
+			}
+		})
+	}
+}
+
+func TestFileBackends(t *testing.T) {
+	tests := []struct {
+		name      string
+		encrypted bool
+	}{
+		{config.EncryptionPlain, false},
+		{config.EncryptionAge, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths := newTestPaths(t)
+			usePassphrase(t, "correct horse battery staple")
+
+			backend, err := Backend(paths, tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if backend.Present() {
+				t.Fatal("a token is present before one was stored")
+			}
+			for _, token := range []string{"first-token", "second-token"} {
+				if err := backend.Store(token); err != nil {
+					t.Fatalf("Store failed: %v", err)
+				}
+				got, err := backend.Retrieve()
+				if err != nil {
+					t.Fatalf("Retrieve failed: %v", err)
+				}
+				if got != token {
+					t.Errorf("Retrieve() = %q, want %q", got, token)
+				}
+			}
+
+			data, err := os.ReadFile(backend.Location())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if encrypted := string(data) != "second-token"; encrypted != tt.encrypted {
+				t.Errorf("the token file is encrypted = %v, want %v", encrypted, tt.encrypted)
+			}
+			if tt.encrypted {
+				usePassphrase(t, "wrong passphrase")
+				if _, err := backend.Retrieve(); !errors.Is(err, ErrDecryption) {
+					t.Errorf("Retrieve with the wrong passphrase = %v, want ErrDecryption", err)
+				}
+			}
+
+			if err := backend.Delete(); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			if backend.Present() {
+				t.Error("the token is present after it was deleted")
+			}
+		})
+	}
+}
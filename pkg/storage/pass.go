@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/jimed-rand/fediscord/pkg/config"
+)
+
+const passStoreDirEnv = "PASSWORD_STORE_DIR"
+
+type passStore struct {
+	paths *config.Paths
+}
+
+func (s *passStore) Name() string        { return config.EncryptionPass }
+func (s *passStore) Description() string { return "pass password store" }
+func (s *passStore) Encrypted() bool     { return true }
+
+func (s *passStore) entry() string {
+	return secretApplication + "/" + s.paths.Profile + "/discord-token"
+}
+
+func (s *passStore) Location() string {
+	return s.entry() + " in " + s.dir()
+}
+
+func (s *passStore) dir() string {
+	if dir := os.Getenv(passStoreDirEnv); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".password-store")
+}
+
+func (s *passStore) Available() error {
+	if _, err := exec.LookPath("pass"); err != nil {
+		return fmt.Errorf("%w: pass is not installed", ErrBackendUnavailable)
+	}
+	if !fileExists(filepath.Join(s.dir(), ".gpg-id")) {
+		return fmt.Errorf("%w: the password store has not been initialised; run pass init", ErrBackendUnavailable)
+	}
+	return nil
+}
+
+func (s *passStore) Present() bool {
+	dir := s.dir()
+	return dir != "" && fileExists(filepath.Join(dir, filepath.FromSlash(s.entry())+".gpg"))
+}
+
+func (s *passStore) Store(token string) error {
+	if err := s.Available(); err != nil {
+		return err
+	}
+	if _, err := runTool(token+"\n", "pass", "insert", "--multiline", "--force", s.entry()); err != nil {
+		return fmt.Errorf("%w: pass insert: %v", ErrBackend, err)
+	}
+	return nil
+}
+
+func (s *passStore) Retrieve() (string, error) {
+	if _, err := exec.LookPath("pass"); err != nil {
+		return "", fmt.Errorf("%w: pass is not installed, however the token is kept in the password store", ErrBackendUnavailable)
+	}
+	out, err := runTool("", "pass", "show", s.entry())
+	if err != nil {
+		return "", fmt.Errorf("%w: pass show: %v", ErrBackend, err)
+	}
+	token, _, _ := strings.Cut(out, "\n")
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", errors.New("the password store entry " + s.entry() + " is empty")
+	}
+	return token, nil
+}
+
+func (s *passStore) Delete() error {
+	if !s.Present() {
+		return nil
+	}
+	if _, err := runTool("", "pass", "rm", "--force", s.entry()); err != nil {
+		return fmt.Errorf("%w: pass rm: %v", ErrBackend, err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/jimed-rand/fediscord/pkg/config"
+)
+
+const fakePass = `#!/bin/sh
+command=$1
+shift
+while [ "${1#--}" != "$1" ]; do
+	shift
+done
+file="$PASSWORD_STORE_DIR/$1.gpg"
+case $command in
+insert)
+	mkdir -p "$(dirname "$file")" && cat >"$file"
+	;;
+show)
+	if [ ! -f "$file" ]; then
+		echo "Error: $1 is not in the password store." >&2
+		exit 1
+	fi
+	cat "$file"
+	;;
+rm)
+	rm "$file"
+	;;
+*)
+	echo "unknown command $command" >&2
+	exit 1
+	;;
+esac
+`
+
+func installFakePass(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake pass is a shell script")
+	}
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "pass"), []byte(fakePass), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestPassStore(t *testing.T) {
+	paths := newTestPaths(t)
+	installFakePass(t)
+	store := os.Getenv(passStoreDirEnv)
+
+	backend, err := Backend(paths, config.EncryptionPass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Available(); !errors.Is(err, ErrBackendUnavailable) {
+		t.Errorf("Available before pass init = %v, want ErrBackendUnavailable", err)
+	}
+	if err := backend.Store("token"); !errors.Is(err, ErrBackendUnavailable) {
+		t.Errorf("Store before pass init = %v, want ErrBackendUnavailable", err)
+	}
+	if err := os.WriteFile(filepath.Join(store, ".gpg-id"), []byte("alice@example.social\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Available(); err != nil {
+		t.Fatalf("Available failed: %v", err)
+	}
+	if backend.Present() {
+		t.Fatal("a token is present before one was stored")
+	}
+	if _, err := backend.Retrieve(); !errors.Is(err, ErrBackend) {
+		t.Errorf("Retrieve before Store = %v, want ErrBackend", err)
+	}
+
+	for _, token := range []string{"first-token", "second-token"} {
+		if err := backend.Store(token); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		got, err := backend.Retrieve()
+		if err != nil {
+			t.Fatalf("Retrieve failed: %v", err)
+		}
+		if got != token {
+			t.Errorf("Retrieve() = %q, want %q", got, token)
+		}
+	}
+	entry := filepath.Join(store, secretApplication, paths.Profile, "discord-token.gpg")
+	if data, err := os.ReadFile(entry); err != nil || string(data) != "second-token\n" {
+		t.Errorf("%s = %q, %v; want %q", entry, data, err, "second-token\n")
+	}
+	if !backend.Present() {
+		t.Error("the stored token is not present")
+	}
+
+	if err := os.WriteFile(entry, []byte("\nnotes\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := backend.Retrieve(); err == nil {
+		t.Error("Retrieve of an entry with an empty first line succeeded")
+	}
+
+	if err := backend.Delete(); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if backend.Present() {
+		t.Error("the token is present after it was deleted")
+	}
+	if err := backend.Delete(); err != nil {
+		t.Errorf("Delete of a missing token failed: %v", err)
+	}
+}
+
+func TestPassStoreNotInstalled(t *testing.T) {
+	paths := newTestPaths(t)
+	t.Setenv("PATH", t.TempDir())
+
+	backend, err := Backend(paths, config.EncryptionPass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Available(); !errors.Is(err, ErrBackendUnavailable) {
+		t.Errorf("Available without pass = %v, want ErrBackendUnavailable", err)
+	}
+	if _, err := backend.Retrieve(); !errors.Is(err, ErrBackendUnavailable) {
+		t.Errorf("Retrieve without pass = %v, want ErrBackendUnavailable", err)
+	}
+}
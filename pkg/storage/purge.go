@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jimed-rand/fediscord/pkg/config"
+)
+
+var ErrPurgeIncomplete = errors.New("some data could not be removed")
+
+type PurgeReport struct {
+	Removed   []PurgedItem `json:"removed"`
+	Kept      []string     `json:"kept,omitempty"`
+	Remaining []string     `json:"remaining,omitempty"`
+}
+
+type PurgedItem struct {
+	Item        string `json:"item"`
+	Location    string `json:"location"`
+	Overwritten bool   `json:"overwritten"`
+}
+
+func (r *PurgeReport) add(item, location string, overwritten bool) {
+	r.Removed = append(r.Removed, PurgedItem{Item: item, Location: location, Overwritten: overwritten})
+}
+
+func DeleteAll(paths *config.Paths) (*PurgeReport, error) {
+	report := &PurgeReport{}
+	unlock, err := paths.Lock()
+	if err != nil {
+		return report, err
+	}
+	names, err := paths.Profiles()
+	if err == nil {
+		err = deleteTokens(paths, names, report)
+	}
+	if err == nil {
+		err = wipeFiles(purgedFiles(paths, names), report)
+	}
+	unlock()
+	if err != nil {
+		return report, err
+	}
+	if err := wipeFiles(lockFiles(paths), report); err != nil {
+		return report, err
+	}
+
+	for _, dir := range purgedDirectories(paths, names) {
+		if !pathExists(dir.path) {
+			continue
+		}
+		if err := os.Remove(dir.path); err != nil {
+			report.Kept = append(report.Kept, dir.path)
+			continue
+		}
+		report.add(dir.item, dir.path, false)
+	}
+
+	report.Remaining = verifyPurge(paths, names)
+	if len(report.Remaining) > 0 {
+		return report, fmt.Errorf("%w: %d item(s) remain", ErrPurgeIncomplete, len(report.Remaining))
+	}
+	return report, nil
+}
+
+func deleteTokens(paths *config.Paths, names []string, report *PurgeReport) error {
+	for _, name := range names {
+		for _, backend := range Backends(paths.ForProfile(name)) {
+			if !backend.Present() {
+				continue
+			}
+			if err := backend.Delete(); err != nil {
+				return fmt.Errorf("the token of profile %s in the %s could not be removed: %w", name, backend.Description(), err)
+			}
+			report.add("Discord token of profile "+name+" ("+backend.Description()+")", backend.Location(), isTokenFile(backend))
+		}
+	}
+	return nil
+}
+
+func wipeFiles(files []string, report *PurgeReport) error {
+	for _, file := range files {
+		if !pathExists(file) {
+			continue
+		}
+		if err := config.WipeFile(file); err != nil {
+			return err
+		}
+		report.add("File", file, true)
+	}
+	return nil
+}
+
+func purgedFiles(paths *config.Paths, names []string) []string {
+	var files []string
+	for _, name := range names {
+		profile := paths.ForProfile(name)
+		for _, token := range []string{profile.TokenAge, profile.TokenGPG, profile.TokenPlain} {
+			files = append(files, token, token+previousSuffix)
+		}
+	}
+	return withTemporaryFiles(append(files, paths.DataFiles()...))
+}
+
+func lockFiles(paths *config.Paths) []string {
+	return withTemporaryFiles([]string{paths.LockFile()})
+}
+
+func withTemporaryFiles(files []string) []string {
+	var all []string
+	for _, file := range files {
+		all = append(all, file)
+		all = append(all, config.TemporaryFiles(file)...)
+	}
+	return all
+}
+
+type purgedDirectory struct {
+	item string
+	path string
+}
+
+func purgedDirectories(paths *config.Paths, names []string) []purgedDirectory {
+	var dirs []purgedDirectory
+	for _, name := range names {
+		dirs = append(dirs, purgedDirectory{"Directory of profile " + name, paths.ForProfile(name).ProfileDir})
+	}
+	dirs = append(dirs,
+		purgedDirectory{"Profiles directory", filepath.Join(paths.Dir, "profiles")},
+		purgedDirectory{"State directory", paths.StateDir},
+		purgedDirectory{"Cache directory", paths.CacheDir},
+	)
+	if !paths.CustomDir() {
+		dirs = append(dirs, purgedDirectory{"Configuration directory", paths.Dir})
+	}
+	if paths.LegacyDir != "" {
+		dirs = append(dirs, purgedDirectory{"Legacy configuration directory", paths.LegacyDir})
+	}
+	return dirs
+}
+
+func verifyPurge(paths *config.Paths, names []string) []string {
+	var remaining []string
+	for _, name := range names {
+		for _, backend := range Backends(paths.ForProfile(name)) {
+			if backend.Present() {
+				remaining = append(remaining, "Discord token of profile "+name+" in the "+backend.Description()+": "+backend.Location())
+			}
+		}
+	}
+	for _, file := range append(purgedFiles(paths, names), lockFiles(paths)...) {
+		if pathExists(file) {
+			remaining = append(remaining, "File: "+file)
+		}
+	}
+	return remaining
+}
+
+func pathExists(path string) bool {
+	_, err := os.Lstat(path)
+	return !os.IsNotExist(err)
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jimed-rand/fediscord/pkg/config"
+)
+
+func TestDeleteAll(t *testing.T) {
+	tests := []struct {
+		name    string
+		foreign map[string]string
+		kept    []string
+	}{
+		{
+			name: "only fediscord data",
+		},
+		{
+			name:    "unrelated file in the configuration directory",
+			foreign: map[string]string{"notes.txt": "keep me"},
+		},
+		{
+			name:    "unrelated file in a profile directory",
+			foreign: map[string]string{filepath.Join("profiles", "work", "notes.txt"): "keep me"},
+			kept:    []string{filepath.Join("profiles", "work"), "profiles"},
+		},
+		{
+			name:    "unrelated file in the state directory",
+			foreign: map[string]string{filepath.Join("state", "notes.txt"): "keep me"},
+			kept:    []string{"state"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths := newTestPaths(t)
+			if err := paths.CreateProfile("work"); err != nil {
+				t.Fatal(err)
+			}
+			for _, profile := range []*config.Paths{paths, paths.ForProfile("work")} {
+				if err := SetBackendPreference(profile, config.EncryptionPlain); err != nil {
+					t.Fatal(err)
+				}
+				if err := StoreToken(profile, "token-of-"+profile.Profile); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, file := range []string{paths.HistoryFile, paths.InstanceCache, paths.TokenPlain + previousSuffix} {
+				if err := config.WriteFileAtomic(file, []byte("data"), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for name, contents := range tt.foreign {
+				path := filepath.Join(paths.Dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			report, err := DeleteAll(paths)
+			if err != nil {
+				t.Fatalf("DeleteAll failed: %v (remaining %v)", err, report.Remaining)
+			}
+
+			for name, contents := range tt.foreign {
+				data, err := os.ReadFile(filepath.Join(paths.Dir, name))
+				if err != nil || string(data) != contents {
+					t.Errorf("the unrelated file %s was not kept: %q, %v", name, data, err)
+				}
+			}
+			var kept []string
+			for _, dir := range report.Kept {
+				rel, _ := filepath.Rel(paths.Dir, dir)
+				kept = append(kept, rel)
+			}
+			if !reflect.DeepEqual(kept, tt.kept) {
+				t.Errorf("Kept = %v, want %v", kept, tt.kept)
+			}
+
+			if _, err := os.Stat(paths.Dir); err != nil {
+				t.Errorf("the configuration directory supplied with %s was removed: %v", config.ConfigDirEnv, err)
+			}
+			for _, file := range []string{
+				paths.ConfigFile, paths.LockFile(), paths.HistoryFile, paths.InstanceCache,
+				paths.TokenPlain, paths.TokenPlain + previousSuffix, paths.ForProfile("work").TokenPlain,
+			} {
+				if fileExists(file) {
+					t.Errorf("%s was not removed", file)
+				}
+			}
+
+			overwritten := map[string]bool{}
+			for _, item := range report.Removed {
+				overwritten[item.Location] = item.Overwritten
+			}
+			for _, file := range []string{paths.TokenPlain, paths.ConfigFile, paths.HistoryFile} {
+				if !overwritten[file] {
+					t.Errorf("the report does not list %s as overwritten", file)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/jimed-rand/fediscord/pkg/config"
+)
+
+const (
+	secretServiceName       = "org.freedesktop.secrets"
+	secretServicePath       = "/org/freedesktop/secrets"
+	secretDefaultCollection = "/org/freedesktop/secrets/aliases/default"
+	secretServiceInterface  = "org.freedesktop.Secret.Service"
+	secretCollectionIface   = "org.freedesktop.Secret.Collection"
+	secretItemInterface     = "org.freedesktop.Secret.Item"
+	secretPromptInterface   = "org.freedesktop.Secret.Prompt"
+	secretSessionInterface  = "org.freedesktop.Secret.Session"
+	sessionBusAddressEnv    = "DBUS_SESSION_BUS_ADDRESS"
+)
+
+var secretPromptTimeout = 2 * time.Minute
+
+var ErrPromptDismissed = errors.New("the keyring prompt was dismissed")
+
+type secret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+type secretService struct {
+	paths *config.Paths
+}
+
+type secretSession struct {
+	conn *dbus.Conn
+	path dbus.ObjectPath
+}
+
+func (s *secretService) Name() string        { return config.EncryptionSecretService }
+func (s *secretService) Description() string { return "Secret Service keyring" }
+func (s *secretService) Encrypted() bool     { return true }
+
+func (s *secretService) Location() string {
+	return "keyring item " + s.label()
+}
+
+func (s *secretService) label() string {
+	return "fediscord Discord token (" + s.paths.Profile + ")"
+}
+
+func (s *secretService) attributes() map[string]string {
+	return map[string]string{
+		"application": secretApplication,
+		"profile":     s.paths.Profile,
+	}
+}
+
+func (s *secretService) Available() error {
+	session, err := openSecretSession()
+	if err != nil {
+		return err
+	}
+	session.close()
+	return nil
+}
+
+func (s *secretService) Present() bool {
+	session, err := openSecretSession()
+	if err != nil {
+		return false
+	}
+	defer session.close()
+
+	unlocked, locked, err := session.search(s.attributes())
+	return err == nil && len(unlocked)+len(locked) > 0
+}
+
+func (s *secretService) Store(token string) error {
+	session, err := openSecretSession()
+	if err != nil {
+		return err
+	}
+	defer session.close()
+
+	properties := map[string]dbus.Variant{
+		"org.freedesktop.Secret.Item.Label":      dbus.MakeVariant(s.label()),
+		"org.freedesktop.Secret.Item.Attributes": dbus.MakeVariant(s.attributes()),
+	}
+	value := secret{Session: session.path, Parameters: []byte{}, Value: []byte(token), ContentType: "text/plain; charset=utf8"}
+
+	var item, prompt dbus.ObjectPath
+	collection := session.conn.Object(secretServiceName, secretDefaultCollection)
+	if err := collection.Call(secretCollectionIface+".CreateItem", 0, properties, value, true).Store(&item, &prompt); err != nil {
+		return fmt.Errorf("%w: the item could not be created: %v", ErrBackend, err)
+	}
+	if prompt != "/" {
+		if _, err := session.prompt(prompt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *secretService) Retrieve() (string, error) {
+	session, err := openSecretSession()
+	if err != nil {
+		return "", err
+	}
+	defer session.close()
+
+	unlocked, locked, err := session.search(s.attributes())
+	if err != nil {
+		return "", err
+	}
+	if len(unlocked) == 0 && len(locked) > 0 {
+		if unlocked, err = session.unlock(locked); err != nil {
+			return "", err
+		}
+	}
+	if len(unlocked) == 0 {
+		return "", ErrTokenNotFound
+	}
+
+	var value secret
+	item := session.conn.Object(secretServiceName, unlocked[0])
+	if err := item.Call(secretItemInterface+".GetSecret", 0, session.path).Store(&value); err != nil {
+		return "", fmt.Errorf("%w: the secret could not be read: %v", ErrBackend, err)
+	}
+	return string(value.Value), nil
+}
+
+func (s *secretService) Delete() error {
+	session, err := openSecretSession()
+	if err != nil {
+		return err
+	}
+	defer session.close()
+
+	unlocked, locked, err := session.search(s.attributes())
+	if err != nil {
+		return err
+	}
+	for _, path := range append(unlocked, locked...) {
+		var prompt dbus.ObjectPath
+		if err := session.conn.Object(secretServiceName, path).Call(secretItemInterface+".Delete", 0).Store(&prompt); err != nil {
+			return fmt.Errorf("%w: the item could not be deleted: %v", ErrBackend, err)
+		}
+		if prompt != "/" {
+			if _, err := session.prompt(prompt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func sessionBusAddress() (string, error) {
+	if address := os.Getenv(sessionBusAddressEnv); address != "" {
+		return address, nil
+	}
+	if uid := os.Getuid(); uid >= 0 {
+		socket := filepath.Join("/run/user", strconv.Itoa(uid), "bus")
+		if fileExists(socket) {
+			return "unix:path=" + socket, nil
+		}
+	}
+	return "", fmt.Errorf("%w: no D-Bus session bus was found", ErrBackendUnavailable)
+}
+
+func openSecretSession() (*secretSession, error) {
+	address, err := sessionBusAddress()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		return nil, fmt.Errorf("%w: the D-Bus session bus could not be reached: %v", ErrBackendUnavailable, err)
+	}
+
+	var output dbus.Variant
+	var path dbus.ObjectPath
+	service := conn.Object(secretServiceName, secretServicePath)
+	if err := service.Call(secretServiceInterface+".OpenSession", 0, "plain", dbus.MakeVariant("")).Store(&output, &path); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w: no Secret Service provider is running: %v", ErrBackendUnavailable, err)
+	}
+	return &secretSession{conn: conn, path: path}, nil
+}
+
+func (s *secretSession) close() {
+	s.conn.Object(secretServiceName, s.path).Call(secretSessionInterface+".Close", 0)
+	s.conn.Close()
+}
+
+func (s *secretSession) search(attributes map[string]string) ([]dbus.ObjectPath, []dbus.ObjectPath, error) {
+	var unlocked, locked []dbus.ObjectPath
+	service := s.conn.Object(secretServiceName, secretServicePath)
+	if err := service.Call(secretServiceInterface+".SearchItems", 0, attributes).Store(&unlocked, &locked); err != nil {
+		return nil, nil, fmt.Errorf("%w: the keyring could not be searched: %v", ErrBackend, err)
+	}
+	return unlocked, locked, nil
+}
+
+func (s *secretSession) unlock(items []dbus.ObjectPath) ([]dbus.ObjectPath, error) {
+	var unlocked []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	service := s.conn.Object(secretServiceName, secretServicePath)
+	if err := service.Call(secretServiceInterface+".Unlock", 0, items).Store(&unlocked, &prompt); err != nil {
+		return nil, fmt.Errorf("%w: the keyring could not be unlocked: %v", ErrBackend, err)
+	}
+	if prompt == "/" {
+		return unlocked, nil
+	}
+
+	result, err := s.prompt(prompt)
+	if err != nil {
+		return nil, err
+	}
+	if paths, ok := result.Value().([]dbus.ObjectPath); ok {
+		return paths, nil
+	}
+	return items, nil
+}
+
+func (s *secretSession) prompt(path dbus.ObjectPath) (dbus.Variant, error) {
+	if err := s.conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(path),
+		dbus.WithMatchInterface(secretPromptInterface),
+		dbus.WithMatchMember("Completed"),
+	); err != nil {
+		return dbus.Variant{}, fmt.Errorf("%w: %v", ErrBackend, err)
+	}
+	signals := make(chan *dbus.Signal, 1)
+	s.conn.Signal(signals)
+	defer s.conn.RemoveSignal(signals)
+
+	if err := s.conn.Object(secretServiceName, path).Call(secretPromptInterface+".Prompt", 0, "").Err; err != nil {
+		return dbus.Variant{}, fmt.Errorf("%w: the keyring prompt could not be shown: %v", ErrBackend, err)
+	}
+
+	timeout := time.After(secretPromptTimeout)
+	for {
+		select {
+		case signal := <-signals:
+			if signal.Path != path || signal.Name != secretPromptInterface+".Completed" || len(signal.Body) != 2 {
+				continue
+			}
+			if dismissed, _ := signal.Body[0].(bool); dismissed {
+				return dbus.Variant{}, ErrPromptDismissed
+			}
+			result, _ := signal.Body[1].(dbus.Variant)
+			return result, nil
+		case <-timeout:
+			return dbus.Variant{}, fmt.Errorf("%w: the keyring prompt was not answered", ErrBackend)
+		}
+	}
+}
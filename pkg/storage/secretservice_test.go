@@ -0,0 +1,389 @@
+package storage
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/jimed-rand/fediscord/pkg/config"
+)
+
+const (
+	promptComplete = iota
+	promptDismiss
+	promptIgnore
+)
+
+const testBusConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:path=%s</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+type fakeItem struct {
+	service    *fakeSecretService
+	path       dbus.ObjectPath
+	attributes map[string]string
+	value      []byte
+	locked     bool
+}
+
+type fakeSecretService struct {
+	mu       sync.Mutex
+	conn     *dbus.Conn
+	items    map[dbus.ObjectPath]*fakeItem
+	next     int
+	prompt   int
+	prompted int
+	pending  map[dbus.ObjectPath]func() dbus.Variant
+}
+
+type fakeCollection struct{ service *fakeSecretService }
+
+type fakePrompt struct {
+	service *fakeSecretService
+	path    dbus.ObjectPath
+}
+
+func startSessionBus(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("dbus-daemon"); err != nil {
+		t.Skip("dbus-daemon is not installed")
+	}
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "session.conf")
+	if err := os.WriteFile(configFile, []byte(fmt.Sprintf(testBusConfig, filepath.Join(dir, "bus"))), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("dbus-daemon", "--config-file="+configFile, "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("dbus-daemon could not be started: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("dbus-daemon did not print its address: %v", err)
+	}
+	return address[:len(address)-1]
+}
+
+func newFakeSecretService(t *testing.T) *fakeSecretService {
+	t.Helper()
+	address := startSessionBus(t)
+	t.Setenv(sessionBusAddressEnv, address)
+
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	service := &fakeSecretService{
+		conn:    conn,
+		items:   map[dbus.ObjectPath]*fakeItem{},
+		pending: map[dbus.ObjectPath]func() dbus.Variant{},
+	}
+	if err := conn.Export(service, secretServicePath, secretServiceInterface); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Export(&fakeCollection{service}, secretDefaultCollection, secretCollectionIface); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := conn.RequestName(secretServiceName, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("the Secret Service name could not be claimed: %v, %v", reply, err)
+	}
+	return service
+}
+
+func (s *fakeSecretService) OpenSession(algorithm string, input dbus.Variant) (dbus.Variant, dbus.ObjectPath, *dbus.Error) {
+	if algorithm != "plain" {
+		return dbus.Variant{}, "", dbus.NewError("org.freedesktop.DBus.Error.NotSupported", []interface{}{algorithm})
+	}
+	return dbus.MakeVariant(""), "/org/freedesktop/secrets/session/1", nil
+}
+
+func (s *fakeSecretService) SearchItems(attributes map[string]string) ([]dbus.ObjectPath, []dbus.ObjectPath, *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlocked, locked := []dbus.ObjectPath{}, []dbus.ObjectPath{}
+	for path, item := range s.items {
+		if !matches(item.attributes, attributes) {
+			continue
+		}
+		if item.locked {
+			locked = append(locked, path)
+		} else {
+			unlocked = append(unlocked, path)
+		}
+	}
+	return unlocked, locked, nil
+}
+
+func (s *fakeSecretService) Unlock(objects []dbus.ObjectPath) ([]dbus.ObjectPath, dbus.ObjectPath, *dbus.Error) {
+	prompt := s.newPrompt(func() dbus.Variant {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, path := range objects {
+			if item, ok := s.items[path]; ok {
+				item.locked = false
+			}
+		}
+		return dbus.MakeVariant(objects)
+	})
+	return []dbus.ObjectPath{}, prompt, nil
+}
+
+func (s *fakeSecretService) newPrompt(complete func() dbus.Variant) dbus.ObjectPath {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next++
+	path := dbus.ObjectPath("/org/freedesktop/secrets/prompt/p" + strconv.Itoa(s.next))
+	s.pending[path] = complete
+	s.conn.Export(&fakePrompt{s, path}, path, secretPromptInterface)
+	return path
+}
+
+func (p *fakePrompt) Prompt(windowID string) *dbus.Error {
+	s := p.service
+	s.mu.Lock()
+	s.prompted++
+	mode := s.prompt
+	complete := s.pending[p.path]
+	s.mu.Unlock()
+
+	go func() {
+		switch mode {
+		case promptComplete:
+			s.conn.Emit(p.path, secretPromptInterface+".Completed", false, complete())
+		case promptDismiss:
+			s.conn.Emit(p.path, secretPromptInterface+".Completed", true, dbus.MakeVariant(""))
+		}
+	}()
+	return nil
+}
+
+func (c *fakeCollection) CreateItem(properties map[string]dbus.Variant, value secret, replace bool) (dbus.ObjectPath, dbus.ObjectPath, *dbus.Error) {
+	s := c.service
+	attributes, _ := properties[secretItemInterface+".Attributes"].Value().(map[string]string)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if replace {
+		for path, item := range s.items {
+			if matches(item.attributes, attributes) {
+				item.value = value.Value
+				return path, "/", nil
+			}
+		}
+	}
+	s.next++
+	path := dbus.ObjectPath("/org/freedesktop/secrets/collection/login/i" + strconv.Itoa(s.next))
+	item := &fakeItem{service: s, path: path, attributes: attributes, value: value.Value}
+	s.items[path] = item
+	s.conn.Export(item, path, secretItemInterface)
+	return path, "/", nil
+}
+
+func (i *fakeItem) GetSecret(session dbus.ObjectPath) (secret, *dbus.Error) {
+	i.service.mu.Lock()
+	defer i.service.mu.Unlock()
+	if i.locked {
+		return secret{}, dbus.NewError("org.freedesktop.Secret.Error.IsLocked", nil)
+	}
+	return secret{Session: session, Parameters: []byte{}, Value: i.value, ContentType: "text/plain"}, nil
+}
+
+func (i *fakeItem) Delete() (dbus.ObjectPath, *dbus.Error) {
+	s := i.service
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, i.path)
+	s.conn.Export(nil, i.path, secretItemInterface)
+	return "/", nil
+}
+
+func (s *fakeSecretService) add(profile, value string, locked bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next++
+	path := dbus.ObjectPath("/org/freedesktop/secrets/collection/login/i" + strconv.Itoa(s.next))
+	item := &fakeItem{
+		service:    s,
+		path:       path,
+		attributes: map[string]string{"application": secretApplication, "profile": profile},
+		value:      []byte(value),
+		locked:     locked,
+	}
+	s.items[path] = item
+	s.conn.Export(item, path, secretItemInterface)
+}
+
+func (s *fakeSecretService) setPrompt(mode int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prompt = mode
+}
+
+func (s *fakeSecretService) counts() (items, prompted int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items), s.prompted
+}
+
+func matches(attributes, query map[string]string) bool {
+	for key, value := range query {
+		if attributes[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSecretService(t *testing.T) {
+	paths := newTestPaths(t)
+	service := newFakeSecretService(t)
+
+	backend, err := Backend(paths, config.EncryptionSecretService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Available(); err != nil {
+		t.Fatalf("Available failed: %v", err)
+	}
+	if backend.Present() {
+		t.Fatal("a token is present before one was stored")
+	}
+	if _, err := backend.Retrieve(); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("Retrieve before Store = %v, want ErrTokenNotFound", err)
+	}
+
+	for _, token := range []string{"first-token", "second-token"} {
+		if err := backend.Store(token); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		got, err := backend.Retrieve()
+		if err != nil {
+			t.Fatalf("Retrieve failed: %v", err)
+		}
+		if got != token {
+			t.Errorf("Retrieve() = %q, want %q", got, token)
+		}
+	}
+	if !backend.Present() {
+		t.Error("the stored token is not present")
+	}
+	if items, _ := service.counts(); items != 1 {
+		t.Errorf("the keyring holds %d items, want 1", items)
+	}
+
+	other, err := Backend(paths.ForProfile("other"), config.EncryptionSecretService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Present() {
+		t.Error("the token of another profile is present")
+	}
+
+	if err := backend.Delete(); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if backend.Present() {
+		t.Error("the token is present after it was deleted")
+	}
+}
+
+func TestSecretServiceLocked(t *testing.T) {
+	previous := secretPromptTimeout
+	secretPromptTimeout = 200 * time.Millisecond
+	t.Cleanup(func() { secretPromptTimeout = previous })
+
+	tests := []struct {
+		name   string
+		prompt int
+		want   error
+	}{
+		{name: "unlocked", prompt: promptComplete},
+		{name: "dismissed", prompt: promptDismiss, want: ErrPromptDismissed},
+		{name: "unanswered", prompt: promptIgnore, want: ErrBackend},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths := newTestPaths(t)
+			service := newFakeSecretService(t)
+			service.add(paths.Profile, "locked-token", true)
+			service.setPrompt(tt.prompt)
+
+			backend, err := Backend(paths, config.EncryptionSecretService)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !backend.Present() {
+				t.Error("the locked token is not present")
+			}
+
+			got, err := backend.Retrieve()
+			if _, prompted := service.counts(); prompted != 1 {
+				t.Errorf("the prompt was shown %d times, want 1", prompted)
+			}
+			if tt.want != nil {
+				if !errors.Is(err, tt.want) {
+					t.Errorf("Retrieve() = %q, %v; want %v", got, err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Retrieve failed: %v", err)
+			}
+			if got != "locked-token" {
+				t.Errorf("Retrieve() = %q, want %q", got, "locked-token")
+			}
+		})
+	}
+}
+
+func TestSecretServiceUnavailable(t *testing.T) {
+	paths := newTestPaths(t)
+	backend, err := Backend(paths, config.EncryptionSecretService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Available(); !errors.Is(err, ErrBackendUnavailable) {
+		t.Errorf("Available without a session bus = %v, want ErrBackendUnavailable", err)
+	}
+	if backend.Present() {
+		t.Error("a token is present without a session bus")
+	}
+
+	t.Setenv(sessionBusAddressEnv, startSessionBus(t))
+	if err := backend.Available(); !errors.Is(err, ErrBackendUnavailable) {
+		t.Errorf("Available without a Secret Service provider = %v, want ErrBackendUnavailable", err)
+	}
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/jimed-rand/fediscord/pkg/config"
+)
+
+const secretApplication = "fediverse-discord"
+
+var (
+	ErrUnknownBackend     = errors.New("the token storage method is not recognised")
+	ErrBackendUnavailable = errors.New("the token storage method is not available on this system")
+	ErrBackend            = errors.New("the secret store operation failed")
+)
+
+type SecretStore interface {
+	Name() string
+	Description() string
+	Encrypted() bool
+	Location() string
+	Available() error
+	Present() bool
+	Store(token string) error
+	Retrieve() (string, error)
+	Delete() error
+}
+
+func Backends(paths *config.Paths) []SecretStore {
+	return []SecretStore{
+		&ageFile{paths: paths},
+		&gpgFile{paths: paths},
+		&secretService{paths: paths},
+		&passStore{paths: paths},
+		&plainFile{paths: paths},
+	}
+}
+
+func Backend(paths *config.Paths, name string) (SecretStore, error) {
+	for _, backend := range Backends(paths) {
+		if backend.Name() == name {
+			return backend, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownBackend, name)
+}
+
+func BackendNames() []string {
+	var names []string
+	for _, backend := range Backends(&config.Paths{}) {
+		names = append(names, backend.Name())
+	}
+	return names
+}
+
+func runTool(stdin string, name string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return "", fmt.Errorf("%v: %s", err, detail)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
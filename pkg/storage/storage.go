@@ -2,116 +2,232 @@ package storage
 
 import (
 	"errors"
-	"os"
-	"os/exec"
-	"runtime"
-	"strings"
+	"fmt"
 
 	"github.com/jimed-rand/fediscord/pkg/config"
+	"github.com/jimed-rand/fediscord/pkg/history"
 )
 
-var ErrNotFound = errors.New("the requested credential was not found in the local configuration store")
+var (
+	ErrNotFound         = errors.New("the requested credential was not found in the local configuration store")
+	ErrTokenNotFound    = fmt.Errorf("no Discord token is stored: %w", ErrNotFound)
+	ErrHandleNotFound   = fmt.Errorf("no Fediverse handle is stored: %w", ErrNotFound)
+	ErrEncryptionNotSet = fmt.Errorf("no token storage method is configured: %w", ErrNotFound)
+	ErrGPG              = errors.New("the GPG operation failed")
+	ErrGPGUnavailable   = errors.New("GPG is not available on this system")
+)
 
-func IsGPGAvailable() bool {
-	if runtime.GOOS == "windows" {
-		return false
+func BackendPreference(paths *config.Paths) (string, error) {
+	cfg, err := paths.ReadConfig()
+	if err != nil {
+		return "", err
 	}
-	_, err := exec.LookPath("gpg")
-	return err == nil
+	name := cfg.Profile(paths.Profile).Encryption
+	if name == "" {
+		return "", ErrEncryptionNotSet
+	}
+	return name, nil
 }
 
-func IsEncryptionEnabled(paths *config.Paths) (bool, error) {
-	data, err := os.ReadFile(paths.EncryptionFlag)
+func ActiveBackend(paths *config.Paths) (SecretStore, error) {
+	name, err := BackendPreference(paths)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	return strings.TrimSpace(string(data)) == "true", nil
+	return Backend(paths, name)
 }
 
-func SetEncryptionPreference(paths *config.Paths, useEncryption bool) error {
-	value := "false"
-	if useEncryption {
-		value = "true"
+func SetBackendPreference(paths *config.Paths, name string) error {
+	if _, err := Backend(paths, name); err != nil {
+		return err
 	}
-	return writeFile(paths.EncryptionFlag, []byte(value), 0600)
-}
-
-func StoreTokenEncrypted(paths *config.Paths, token string) error {
-	cmd := exec.Command("gpg", "--symmetric", "--cipher-algo", "AES256", "--output", paths.TokenEncrypted)
-	cmd.Stdin = strings.NewReader(token)
-	if err := cmd.Run(); err != nil {
+	if err := updateProfile(paths, func(profile *config.Profile) {
+		profile.Encryption = name
+	}); err != nil {
 		return err
 	}
-	if err := os.Chmod(paths.TokenEncrypted, 0600); err != nil {
+	history.Record(paths, "token storage method set to "+name)
+	return nil
+}
+
+func ClearBackendPreference(paths *config.Paths) error {
+	if err := updateProfile(paths, func(profile *config.Profile) {
+		profile.Encryption = ""
+	}); err != nil {
 		return err
 	}
-	os.Remove(paths.TokenPlain)
+	history.Record(paths, "token storage method cleared")
 	return nil
 }
 
-func StoreTokenPlain(paths *config.Paths, token string) error {
-	if err := writeFile(paths.TokenPlain, []byte(token), 0600); err != nil {
+func StoreToken(paths *config.Paths, token string) error {
+	unlock, err := paths.Lock()
+	if err != nil {
 		return err
 	}
-	os.Remove(paths.TokenEncrypted)
+	defer unlock()
+
+	backend, err := ActiveBackend(paths)
+	if err != nil {
+		return err
+	}
+	if err := backend.Available(); err != nil {
+		return err
+	}
+	if err := backend.Store(token); err != nil {
+		return err
+	}
+	if err := deleteOthers(paths, backend); err != nil {
+		return err
+	}
+	history.Record(paths, "Discord token stored ("+backend.Name()+")")
 	return nil
 }
 
 func RetrieveToken(paths *config.Paths) (string, error) {
-	if fileExists(paths.TokenEncrypted) {
-		if !IsGPGAvailable() {
-			return "", errors.New("GPG is not available on this system, however an encrypted token was detected; please install GPG to proceed")
-		}
-		out, err := exec.Command("gpg", "--decrypt", "--quiet", paths.TokenEncrypted).Output()
-		if err != nil {
-			return "", err
-		}
-		return strings.TrimSpace(string(out)), nil
+	backend, err := LocateToken(paths)
+	if err != nil {
+		return "", err
 	}
+	return backend.Retrieve()
+}
 
-	if fileExists(paths.TokenPlain) {
-		data, err := os.ReadFile(paths.TokenPlain)
-		if err != nil {
-			return "", err
+func LocateToken(paths *config.Paths) (SecretStore, error) {
+	if backend, err := ActiveBackend(paths); err == nil && backend.Present() {
+		return backend, nil
+	}
+	for _, backend := range Backends(paths) {
+		if backend.Present() {
+			return backend, nil
 		}
-		return strings.TrimSpace(string(data)), nil
 	}
-
-	return "", ErrNotFound
+	return nil, ErrTokenNotFound
 }
 
-func StoreHandle(paths *config.Paths, handle string) error {
-	return writeFile(paths.HandleFile, []byte(handle), 0600)
-}
+func MigrateToken(paths *config.Paths, name string) error {
+	target, err := Backend(paths, name)
+	if err != nil {
+		return err
+	}
+	if err := target.Available(); err != nil {
+		return err
+	}
 
-func RetrieveHandle(paths *config.Paths) (string, error) {
-	if !fileExists(paths.HandleFile) {
-		return "", ErrNotFound
+	unlock, err := paths.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	source, err := LocateToken(paths)
+	if errors.Is(err, ErrTokenNotFound) || (err == nil && source.Name() == target.Name()) {
+		return SetBackendPreference(paths, name)
 	}
-	data, err := os.ReadFile(paths.HandleFile)
 	if err != nil {
-		return "", err
+		return err
+	}
+
+	token, err := source.Retrieve()
+	if err != nil {
+		return fmt.Errorf("the token could not be read from the %s: %w", source.Description(), err)
+	}
+	if err := target.Store(token); err != nil {
+		return fmt.Errorf("the token could not be stored in the %s: %w", target.Description(), err)
+	}
+	if err := SetBackendPreference(paths, name); err != nil {
+		return err
+	}
+	if err := deleteOthers(paths, target); err != nil {
+		return fmt.Errorf("the token was copied to the %s, however %w", target.Description(), err)
 	}
-	return strings.TrimSpace(string(data)), nil
+	history.Record(paths, "Discord token moved from "+source.Name()+" to "+target.Name())
+	return nil
 }
 
-func DeleteAll(paths *config.Paths) error {
-	return os.RemoveAll(paths.Dir)
+func RenameToken(from, to *config.Paths) error {
+	unlock, err := from.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for _, backend := range Backends(from) {
+		if !backend.Present() {
+			continue
+		}
+		token, err := backend.Retrieve()
+		if err != nil {
+			return err
+		}
+		renamed, _ := Backend(to, backend.Name())
+		if err := renamed.Store(token); err != nil {
+			return err
+		}
+		if err := backend.Delete(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func IsEncryptedTokenPresent(paths *config.Paths) bool {
-	return fileExists(paths.TokenEncrypted)
+func DeleteToken(paths *config.Paths) error {
+	unlock, err := paths.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return deleteOthers(paths, nil)
 }
 
-func IsPlainTokenPresent(paths *config.Paths) bool {
-	return fileExists(paths.TokenPlain)
+func deleteOthers(paths *config.Paths, keep SecretStore) error {
+	for _, backend := range Backends(paths) {
+		if keep != nil && backend.Name() == keep.Name() {
+			continue
+		}
+		if !backend.Present() {
+			continue
+		}
+		if err := backend.Delete(); err != nil {
+			return fmt.Errorf("the copy of the token in the %s could not be removed: %w", backend.Description(), err)
+		}
+	}
+	return nil
 }
 
-func fileExists(path string) bool {
-	_, err := os.Stat(path)
-	return !os.IsNotExist(err)
+func StoreHandle(paths *config.Paths, handle string) error {
+	if err := updateProfile(paths, func(profile *config.Profile) {
+		profile.Handle = handle
+	}); err != nil {
+		return err
+	}
+	history.Record(paths, "Fediverse handle set to @"+handle)
+	return nil
+}
+
+func RetrieveHandle(paths *config.Paths) (string, error) {
+	cfg, err := paths.ReadConfig()
+	if err != nil {
+		return "", err
+	}
+	handle := cfg.Profile(paths.Profile).Handle
+	if handle == "" {
+		return "", ErrHandleNotFound
+	}
+	return handle, nil
 }
 
-func writeFile(path string, data []byte, perm os.FileMode) error {
-	return os.WriteFile(path, data, perm)
+func updateProfile(paths *config.Paths, update func(*config.Profile)) error {
+	unlock, err := paths.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cfg, err := paths.ReadConfig()
+	if err != nil {
+		return err
+	}
+	update(cfg.Profile(paths.Profile))
+	return paths.WriteConfig(cfg)
 }
@@ -18,3 +18,7 @@ func ClearScreen() string {
 func IsTerminal() bool {
 	return term.IsTerminal(int(os.Stdout.Fd()))
 }
+
+func IsInputTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
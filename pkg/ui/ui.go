@@ -2,58 +2,75 @@ package ui
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
 	"github.com/jimed-rand/fediscord/pkg/terminal"
 )
 
+var ErrCancelled = errors.New("the operation was cancelled by the user")
+
+var out io.Writer = os.Stdout
+
+func SetOutput(w io.Writer) {
+	out = w
+}
+
 func PrintHeader(title string) {
-	fmt.Print(terminal.ClearScreen())
+	fmt.Fprint(out, terminal.ClearScreen())
 	width := 59
 	padding := width - len(title) - 2
 	if padding < 0 {
 		padding = 0
 	}
-	fmt.Println("╔═══════════════════════════════════════════════════════════╗")
-	fmt.Println("║  Fediverse to Discord Connection Tool (Mastodon API)     ║")
-	fmt.Println("╠═══════════════════════════════════════════════════════════╣")
-	fmt.Printf("║  %s%s║\n", title, strings.Repeat(" ", padding))
-	fmt.Println("╚═══════════════════════════════════════════════════════════╝")
-	fmt.Println()
+	fmt.Fprintln(out, "╔═══════════════════════════════════════════════════════════╗")
+	fmt.Fprintln(out, "║  Fediverse to Discord Connection Tool (Mastodon API)     ║")
+	fmt.Fprintln(out, "╠═══════════════════════════════════════════════════════════╣")
+	fmt.Fprintf(out, "║  %s%s║\n", title, strings.Repeat(" ", padding))
+	fmt.Fprintln(out, "╚═══════════════════════════════════════════════════════════╝")
+	fmt.Fprintln(out)
 }
 
 func PrintMenu() {
-	fmt.Println("1) Set Up Configuration (Discord Token + Fediverse Handle)")
-	fmt.Println("2) Generate Connection URL")
-	fmt.Println("3) View Stored Configuration")
-	fmt.Println("4) Update Discord Token")
-	fmt.Println("5) Update Fediverse Handle")
-	fmt.Println("6) Change Encryption Settings")
-	fmt.Println("7) Delete All Data")
-	fmt.Println("8) Exit")
-	fmt.Println()
-	fmt.Println("───────────────────────────────────────────────────────────")
-	fmt.Println("Compatible Platforms (Mastodon API):")
-	fmt.Println("  + Mastodon  + Akkoma  + Pleroma  + GlitchSoc  + Hometown")
-	fmt.Println("Incompatible Platforms:")
-	fmt.Println("  - Misskey  - Firefish  - Calckey  - Foundkey")
-	fmt.Println("───────────────────────────────────────────────────────────")
-	fmt.Println()
+	fmt.Fprintln(out, "1) Set Up Configuration (Discord Token + Fediverse Handle)")
+	fmt.Fprintln(out, "2) Generate Connection URL")
+	fmt.Fprintln(out, "3) View Stored Configuration")
+	fmt.Fprintln(out, "4) Update Discord Token")
+	fmt.Fprintln(out, "5) Update Fediverse Handle")
+	fmt.Fprintln(out, "6) Change Encryption Settings")
+	fmt.Fprintln(out, "7) Delete All Data")
+	fmt.Fprintln(out, "8) Verify Discord Token")
+	fmt.Fprintln(out, "9) List Discord Connections")
+	fmt.Fprintln(out, "10) Manage Mastodon Connection")
+	fmt.Fprintln(out, "11) Instance Report")
+	fmt.Fprintln(out, "12) Manage Profiles")
+	fmt.Fprintln(out, "13) Export or Import Backup")
+	fmt.Fprintln(out, "14) Run Diagnostics")
+	fmt.Fprintln(out, "15) Exit")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "───────────────────────────────────────────────────────────")
+	fmt.Fprintln(out, "Compatible Platforms (Mastodon API):")
+	fmt.Fprintln(out, "  + Mastodon  + Glitch  + Hometown  + Akkoma  + Pleroma  + GoToSocial")
+	fmt.Fprintln(out, "Incompatible Platforms:")
+	fmt.Fprintln(out, "  - Misskey  - Firefish  - Calckey  - Foundkey  - Sharkey  - Iceshrimp")
+	fmt.Fprintln(out, "───────────────────────────────────────────────────────────")
+	fmt.Fprintln(out)
 }
 
 func Prompt(label string) string {
-	fmt.Print(label)
+	fmt.Fprint(out, label)
 	reader := bufio.NewReader(os.Stdin)
 	line, _ := reader.ReadString('\n')
 	return strings.TrimSpace(line)
 }
 
 func PromptSecret(label string) (string, error) {
-	fmt.Print(label)
+	fmt.Fprint(out, label)
 	raw, err := terminal.ReadPassword()
-	fmt.Println()
+	fmt.Fprintln(out)
 	if err != nil {
 		return "", err
 	}
@@ -70,21 +87,21 @@ func PressEnter() {
 }
 
 func Info(msg string) {
-	fmt.Println(msg)
+	fmt.Fprintln(out, msg)
 }
 
 func Success(msg string) {
-	fmt.Println("[OK] " + msg)
+	fmt.Fprintln(out, "[OK] "+msg)
 }
 
 func Warn(msg string) {
-	fmt.Println("[!!] " + msg)
+	fmt.Fprintln(out, "[!!] "+msg)
 }
 
 func Error(msg string) {
-	fmt.Println("[ERR] " + msg)
+	fmt.Fprintln(out, "[ERR] "+msg)
 }
 
 func Separator() {
-	fmt.Println("───────────────────────────────────────────────────────────")
+	fmt.Fprintln(out, "───────────────────────────────────────────────────────────")
 }